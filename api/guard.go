@@ -0,0 +1,40 @@
+package auacornapi
+
+// AcornGuard is what a guard proxy gets from the registry, see GuardAdapter.
+//
+// It implements Acorn by forwarding to the guarded instance, so a proxy can embed it, and only needs to implement
+// the methods of the guarded Acorn's primary interface.
+type AcornGuard interface {
+	Acorn
+
+	// Check must be called at the beginning of every method of the proxy, with the name of the method.
+	//
+	// If the guarded Acorn is not set up yet, or already torn down, the registry records a violation or panics,
+	// depending on how it was configured.
+	Check(method string)
+
+	// Target returns the guarded instance, so the proxy can forward the call after checking it.
+	Target() Acorn
+}
+
+// GuardAdapter wraps a guarded Acorn in a proxy that implements the same primary interface,
+// and calls guard.Check() in each of its methods before forwarding the call to guard.Target().
+//
+// The proxy must be a pointer. Library authors can provide a GuardAdapter next to their Acorn, so applications
+// can register it with RegisterGuardAdapter().
+//
+// Example:
+//
+//	type guardedConfiguration struct {
+//		auacornapi.AcornGuard
+//	}
+//
+//	func (g *guardedConfiguration) GetString(key string) string {
+//		g.Check("GetString")
+//		return g.Target().(Configuration).GetString(key)
+//	}
+//
+//	func GuardAdapter(guard auacornapi.AcornGuard) auacornapi.Acorn {
+//		return &guardedConfiguration{AcornGuard: guard}
+//	}
+type GuardAdapter func(guard AcornGuard) Acorn
@@ -1,5 +1,7 @@
 package auacornapi
 
+import "context"
+
 type Constructor func() Acorn
 
 type AcornRegistry interface {
@@ -7,7 +9,7 @@ type AcornRegistry interface {
 
 	// Register an Acorn's constructor with the registry.
 	//
-	// During the first phase, creation, the registry calls them all in unspecified order.
+	// During the first phase, creation, the registry calls them all in registration order.
 	//
 	// Your constructor MUST NOT assume that any other acorn is present. This is just to get non-nil
 	// instance pointers for all Acorns.
@@ -19,27 +21,39 @@ type AcornRegistry interface {
 	// Create should be called after all Acorns have been registered with Register().
 	//
 	// It will use the registered constructors to create uninitialized instances of all registered Acorns.
+	// Constructors registered with RegisterIf() or RegisterFor() whose condition is not met are skipped.
+	//
+	// If two constructors registered with Register() produce Acorns with the same AcornName(), the last one wins.
+	// Depending on the registry's duplicate policy, this is silently allowed (the default), reported as a warning,
+	// or makes Create() fail with an error.
 	//
 	// This does phase one, creation.
-	Create()
+	Create() error
 
 	// Assemble should be called after Create.
 	//
-	// It will call AssembleAcorn on each Acorn.
+	// It will call AssembleAcorn on each Acorn, by default in registration order.
+	//
+	// If any Acorn looked up an unknown Acorn using GetAcornByName, Assemble() fails with an error
+	// listing all of these lookups.
+	//
+	// At the end, it reads the dependencies of all Acorns that implement AcornWithDependencies, and fails
+	// if they contain unknown names, or if they form a cycle together with the order rules.
 	//
 	// This does phase two, assembly.
 	Assemble() error
 
 	// Setup should be called after Assemble.
 	//
-	// It will call SetupAcorn on each Acorn.
+	// It will call SetupAcorn on each Acorn, one by one unless the registry was configured for parallel setup.
 	//
 	// This does phase three, setup.
 	Setup() error
 
 	// Teardown should be called during application shutdown.
 	//
-	// It will call TeardownAcorn on each Acorn.
+	// It will call TeardownAcorn on each Acorn, by default one by one in reverse order of setup completion.
+	// If some of them fail, it still continues with the others, and returns all failures joined into one error.
 	//
 	// This does phase four, teardown.
 	Teardown() error
@@ -51,7 +65,11 @@ type AcornRegistry interface {
 	// Should ONLY be used during the second phase, assembly, meaning in your implementation of AssembleAcorn().
 	//
 	// You have no guarantee that the other acorn has been assembled yet, so all you are allowed to do at this
-	// point is store the reference in your instance. You are guaranteed that the return value is not nil.
+	// point is store the reference in your instance.
+	//
+	// If there is no Acorn of that name, you get nil, but the registry remembers the lookup, and Assemble() will
+	// fail with an error listing all unresolved lookups and the Acorns that made them. So as long as Assemble()
+	// succeeds, you are guaranteed that the return value was not nil.
 	//
 	// DO NOT call any methods on the other Acorn yet. It may not be ready!
 	GetAcornByName(acornName string) Acorn
@@ -59,17 +77,28 @@ type AcornRegistry interface {
 	// SetupAfter allows you to specify that your SetupAcorn() method depends on another Acorn being set up first.
 	//
 	// Should ONLY be used during the third phase, setup, typically at the beginning of your SetupAcorn().
+	// Call it on the registry passed to your SetupAcorn(), not on a global one, so the registry knows
+	// which Acorn is waiting. Otherwise it fails.
 	//
 	// When it returns, you can rely on the other Acorn being set up.
 	//
+	// It is safe to call from multiple goroutines. If another goroutine is already setting up the other Acorn,
+	// it waits for that to finish, rather than setting it up a second time.
+	//
 	// It is an error to create a circular dependency. The registry will detect this.
 	SetupAfter(otherAcorn Acorn) error
 
 	// TeardownAfter allows you to specify that your TeardownAcorn() method depends on another Acorn being torn down first.
 	//
 	// Should ONLY be used during the teardown phase, typically at the beginning of your TeardownAcorn().
+	// Call it on the registry passed to your TeardownAcorn(), not on a global one, so the registry knows
+	// which Acorn is waiting. Otherwise it fails.
+	//
+	// When it returns, you can rely on the other Acorn being torn down. If its teardown failed, that failure
+	// is returned by Teardown(), not here, so you can go on with your own teardown.
 	//
-	// When it returns, you can rely on the other Acorn being torn down.
+	// It is safe to call from multiple goroutines. If another goroutine is already tearing down the other Acorn,
+	// it waits for that to finish, rather than tearing it down a second time.
 	//
 	// It is an error to create a circular dependency. The registry will detect this.
 	TeardownAfter(otherAcorn Acorn) error
@@ -86,6 +115,18 @@ type AcornRegistry interface {
 	// It is an error to create a circular dependency. The registry will detect this.
 	AddSetupOrderRule(prerequisite Acorn, dependency Acorn) error
 
+	// AddTeardownOrderRule allows you to add extra teardown dependencies.
+	//
+	// Effectively this is just like adding TeardownAfter(first) to the TeardownAcorn() method of then.
+	//
+	// This is useful if you are using an Acorn from a library, and it is therefore hard to add the TeardownAfter()
+	// call. With this, you can make sure the library Acorn is torn down before your Acorn that it relies on.
+	//
+	// Should ONLY be used during the assembly phase, typically at the end of AssembleAcorn().
+	//
+	// It is an error to create a circular dependency. The registry will detect this.
+	AddTeardownOrderRule(first Acorn, then Acorn) error
+
 	// --- methods useful for testing ---
 
 	// CreateOverride lets you override an instance after create.
@@ -111,6 +152,99 @@ type AcornRegistry interface {
 	SkipTeardown(instance Acorn)
 }
 
+// ExtendedAcornRegistry is an optional interface for registries that offer more than AcornRegistry.
+//
+// The registry provided by this library implements it, and so does the registry passed to the lifecycle
+// methods of your Acorns. Use a type assertion to get at it, so your code keeps working with registries
+// that only implement AcornRegistry, such as mocks in tests.
+type ExtendedAcornRegistry interface {
+	AcornRegistry
+
+	// --- methods to be called by the top level application ---
+
+	// RegisterOverride registers an Acorn's constructor that deliberately replaces an earlier registration
+	// with the same AcornName().
+	//
+	// Unlike Register(), this is never reported as a duplicate, regardless of the registry's duplicate policy.
+	RegisterOverride(constructor Constructor)
+
+	// RegisterIf registers an Acorn's constructor that is only used if predicate returns true.
+	//
+	// The predicate is evaluated during Create(), so it can depend on anything set up before that, e.g. flags.
+	RegisterIf(predicate func() bool, constructor Constructor)
+
+	// RegisterFor registers an Acorn's constructor that is only used if profile is active, e.g. "prod".
+	//
+	// Whether the profile is active is decided during Create(), see ActivateProfiles().
+	RegisterFor(profile string, constructor Constructor)
+
+	// ActivateProfiles activates profiles for RegisterFor(). Call it before Create(), in any order with the
+	// registrations. Each call adds to the profiles already active.
+	ActivateProfiles(profiles ...string)
+
+	// RegisterLazy registers the constructor of an Acorn that is only created, assembled and set up if another
+	// Acorn looks it up during assembly. acornName must be the AcornName() of the Acorn the constructor creates,
+	// otherwise Assemble() fails.
+	//
+	// Use it for expensive Acorns that are only needed by some configurations of your application.
+	RegisterLazy(acornName string, constructor Constructor)
+
+	// RegisterGuardAdapter registers a GuardAdapter for the Acorn with the given name.
+	//
+	// If the registry was configured to guard dependencies, GetAcornByName hands out the proxy created by
+	// the adapter to other Acorns instead of the instance itself. The proxy detects methods being called
+	// before the Acorn is set up, or after it was torn down. Otherwise, the adapter is never used.
+	RegisterGuardAdapter(acornName string, adapter GuardAdapter)
+
+	// AssembleCtx is Assemble, but does not call any more Acorns once ctx is done.
+	//
+	// Acorns that implement ContextAcorn receive a context derived from ctx.
+	AssembleCtx(ctx context.Context) error
+
+	// SetupCtx is Setup, but does not start setting up any more Acorns once ctx is done.
+	//
+	// Acorns that implement ContextAcorn receive a context derived from ctx.
+	SetupCtx(ctx context.Context) error
+
+	// Start should be called after Setup, if any of your Acorns implement StartableAcorn.
+	//
+	// It will call StartAcorn on each of them, in order of setup completion. This is when servers, consumers
+	// and schedulers should begin accepting work, because now every Acorn is set up.
+	Start() error
+
+	// StartCtx is Start, but does not start any more Acorns once ctx is done.
+	StartCtx(ctx context.Context) error
+
+	// Stop should be called after Start, at the beginning of application shutdown.
+	//
+	// It will call StopAcorn on each Acorn that implements StoppableAcorn, in reverse order of setup completion,
+	// which is also the reverse order of starting. Acorns that also implement StartableAcorn are only stopped
+	// if they were started. If some of them fail, it still continues with the others, and returns all failures joined.
+	//
+	// If you call Teardown without calling Stop first, Teardown does it for you.
+	Stop() error
+
+	// StopCtx is Stop, but does not stop any more Acorns once ctx is done.
+	StopCtx(ctx context.Context) error
+
+	// TeardownCtx is Teardown, but does not start tearing down any more Acorns once ctx is done.
+	//
+	// Acorns that implement ContextAcorn receive a context derived from ctx.
+	TeardownCtx(ctx context.Context) error
+
+	// --- methods to be called by Acorns ---
+
+	// SetupAfterCtx is SetupAfter, but gives up once ctx is done.
+	//
+	// When called with the registry passed to your SetupAcorn(), SetupAfter already uses your Acorn's context.
+	SetupAfterCtx(ctx context.Context, otherAcorn Acorn) error
+
+	// TeardownAfterCtx is TeardownAfter, but gives up once ctx is done.
+	//
+	// When called with the registry passed to your TeardownAcorn(), TeardownAfter already uses your Acorn's context.
+	TeardownAfterCtx(ctx context.Context, otherAcorn Acorn) error
+}
+
 type Acorn interface {
 	// AcornName should return the package name, followed by the Acorn's primary interface name, and possibly
 	// a third part for disambiguation, separated by a dot (".").
@@ -153,3 +287,62 @@ type Acorn interface {
 	// decide which dependency to sever, because we must tear down in SOME order.
 	TeardownAcorn(registry AcornRegistry) error
 }
+
+// AcornWithDependencies is an optional interface for Acorns that declare their dependencies by name.
+//
+// The registry reads the declared dependencies at the end of Assemble(). This way, it knows the dependency graph
+// before any SetupAcorn() is called, and can detect circular dependencies up front. You can still call
+// SetupAfter() and TeardownAfter() in addition to declaring dependencies.
+type AcornWithDependencies interface {
+	Acorn
+
+	// SetupDependencies returns the names of the Acorns that must be set up before this one.
+	//
+	// Effectively this is just like calling SetupAfter() for each of them at the beginning of SetupAcorn().
+	SetupDependencies() []string
+
+	// TeardownDependencies returns the names of the Acorns that must be torn down before this one.
+	//
+	// Effectively this is just like calling TeardownAfter() for each of them at the beginning of TeardownAcorn().
+	TeardownDependencies() []string
+}
+
+// ContextAcorn is an optional interface for Acorns that want a context in their lifecycle methods.
+//
+// If an Acorn implements it, the registry calls these methods instead of AssembleAcorn, SetupAcorn and
+// TeardownAcorn. The context is done when the Acorn's own timeout or the timeout of the whole phase runs out,
+// or when the context passed to AssembleCtx, SetupCtx or TeardownCtx is canceled. Return promptly when it is.
+type ContextAcorn interface {
+	Acorn
+
+	// AssembleAcornCtx is AssembleAcorn with a context.
+	AssembleAcornCtx(ctx context.Context, registry AcornRegistry) error
+
+	// SetupAcornCtx is SetupAcorn with a context.
+	SetupAcornCtx(ctx context.Context, registry AcornRegistry) error
+
+	// TeardownAcornCtx is TeardownAcorn with a context.
+	TeardownAcornCtx(ctx context.Context, registry AcornRegistry) error
+}
+
+// StartableAcorn is an optional interface for Acorns that do long-running work, such as serving requests.
+type StartableAcorn interface {
+	Acorn
+
+	// StartAcorn gets called during Start(), after all Acorns are set up.
+	//
+	// Begin accepting work here, typically by starting a goroutine, and return promptly.
+	StartAcorn(registry AcornRegistry) error
+}
+
+// StoppableAcorn is an optional interface for Acorns that need to stop accepting work before anything is torn down.
+type StoppableAcorn interface {
+	Acorn
+
+	// StopAcorn gets called during Stop(), before any Acorn is torn down.
+	//
+	// Stop accepting new work here. Release resources in TeardownAcorn as usual.
+	//
+	// If your Acorn does not implement StartableAcorn, it gets called as long as it was set up.
+	StopAcorn(registry AcornRegistry) error
+}
@@ -0,0 +1,53 @@
+package auacornapi
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Get looks up another Acorn by name and type-casts it to its primary interface T.
+//
+// Intended for use in AssembleAcorn(), as a replacement for casting the result of registry.GetAcornByName()
+// yourself. Pass your own instance as requester, it is only used for the error message.
+//
+// If no Acorn of that name is known to the registry, or it does not implement T, you get a descriptive
+// error instead of a panic on the type assertion.
+//
+// Works against any AcornRegistry.
+func Get[T any](registry AcornRegistry, requester Acorn, acornName string) (T, error) {
+	var zero T
+
+	instance := registry.GetAcornByName(acornName)
+	if instance == nil {
+		return zero, fmt.Errorf("acorn %s requested acorn '%s' as %s, but no acorn of that name is registered",
+			requesterName(requester), acornName, typeName[T]())
+	}
+
+	typed, ok := instance.(T)
+	if !ok {
+		return zero, fmt.Errorf("acorn %s requested acorn '%s' as %s, but its type %T does not implement it",
+			requesterName(requester), acornName, typeName[T](), instance)
+	}
+	return typed, nil
+}
+
+// MustGet is like Get, but panics with the descriptive error instead of returning it.
+func MustGet[T any](registry AcornRegistry, requester Acorn, acornName string) T {
+	typed, err := Get[T](registry, requester, acornName)
+	if err != nil {
+		panic(err)
+	}
+	return typed
+}
+
+func requesterName(requester Acorn) string {
+	if requester == nil {
+		return "<unknown>"
+	}
+	return "'" + requester.AcornName() + "'"
+}
+
+func typeName[T any]() string {
+	// formatting a nil pointer to T also works if T is an interface type
+	return strings.TrimPrefix(fmt.Sprintf("%T", (*T)(nil)), "*")
+}
@@ -0,0 +1,106 @@
+package auacorn
+
+import (
+	auacornapi "github.com/StephanHCB/go-autumn-acorn-registry/api"
+	"github.com/StephanHCB/go-autumn-acorn-registry/test/mocka"
+	"github.com/StephanHCB/go-autumn-acorn-registry/test/mockb"
+	"github.com/StephanHCB/go-autumn-acorn-registry/test/mockc"
+	"github.com/StephanHCB/go-autumn-acorn-registry/test/para"
+	"github.com/StephanHCB/go-autumn-acorn-registry/test/rec"
+	"sync"
+	"testing"
+	"time"
+)
+
+// run these with -race to get the full benefit
+
+func TestConcurrency_LookupsDuringTeardown(t *testing.T) {
+	Registry = New()
+
+	Registry.Register(mocka.New)
+	Registry.Register(mockb.New)
+	Registry.Register(mockc.New)
+	Registry.Create()
+	_ = Registry.Assemble()
+	_ = Registry.Setup()
+
+	stop := make(chan struct{})
+	wg := sync.WaitGroup{}
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-stop:
+					return
+				default:
+					if Registry.GetAcornByName(mockc.MockCName) == nil {
+						t.Error("lookup failed")
+						return
+					}
+				}
+			}
+		}()
+	}
+
+	err := Registry.Teardown()
+	close(stop)
+	wg.Wait()
+	if err != nil {
+		t.FailNow()
+	}
+}
+
+func TestConcurrency_SetupAfterFromSpawnedGoroutines(t *testing.T) {
+	Registry = New()
+
+	Registry.Register(para.New(&para.ParaImpl{Name: "x", SetupAfterNames: []string{"y", "z", "w"}, Spawn: true}))
+	Registry.Register(para.New(&para.ParaImpl{Name: "y", SetupAfterNames: []string{"w"}, Delay: 10 * time.Millisecond}))
+	Registry.Register(para.New(&para.ParaImpl{Name: "z", SetupAfterNames: []string{"w"}, Delay: 10 * time.Millisecond}))
+	Registry.Register(para.New(&para.ParaImpl{Name: "w", Delay: 20 * time.Millisecond}))
+	Registry.Create()
+	_ = Registry.Assemble()
+
+	rec.Reset()
+	err := Registry.Setup()
+	if err != nil {
+		t.Errorf("unexpected error: %s", err.Error())
+	}
+	for _, name := range []string{"x", "y", "z", "w"} {
+		if countRecorded(name+".SetupAcorn") != 1 {
+			t.Errorf("%s was not set up exactly once: %v", name, rec.Get())
+		}
+	}
+	if indexRecorded("x.SetupAcorn") != 7 {
+		t.Errorf("x was not set up last: %v", rec.Get())
+	}
+}
+
+func TestConcurrency_RegistrationAndLookupsFromManyGoroutines(t *testing.T) {
+	Registry = New()
+
+	wg := sync.WaitGroup{}
+	for _, constructor := range []auacornapi.Constructor{mocka.New, mockb.New, mockc.New} {
+		wg.Add(1)
+		go func(constructor auacornapi.Constructor) {
+			defer wg.Done()
+			Registry.Register(constructor)
+		}(constructor)
+	}
+	wg.Wait()
+	if Registry.Create() != nil || Registry.Assemble() != nil || Registry.Setup() != nil {
+		t.FailNow()
+	}
+
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if Registry.GetAcornByName(mocka.MockAName) == nil {
+				t.Error("lookup failed")
+			}
+		}()
+	}
+	wg.Wait()
+}
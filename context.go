@@ -0,0 +1,91 @@
+package auacorn
+
+import (
+	"context"
+	"errors"
+	auacornapi "github.com/StephanHCB/go-autumn-acorn-registry/api"
+	"time"
+)
+
+// withPhaseTimeout applies the configured overall timeout, if any, to a registry phase.
+func (a *AcornRegistryImpl) withPhaseTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	if a.phaseTimeout > 0 {
+		return context.WithTimeout(ctx, a.phaseTimeout)
+	}
+	return context.WithCancel(ctx)
+}
+
+// callAcorn calls the lifecycle method of instance for the given phase, preferring the ContextAcorn variant.
+// For start and stop, the instance must implement StartableAcorn or StoppableAcorn.
+//
+// The instance gets its own context, limited by the configured per-acorn timeout. If the context is done
+// before the call, the instance is not called at all. If it is done by the time the call returns, the result
+// is a TimeoutError, unless the instance returned an unrelated error or a TimeoutError of some other acorn.
+//
+// Listeners are notified around each call, and its duration is recorded for the TimingReport.
+func (a *AcornRegistryImpl) callAcorn(ctx context.Context, phase Phase, instance auacornapi.Acorn) error {
+	if err := ctx.Err(); err != nil {
+		return &TimeoutError{Phase: phase, AcornName: instance.AcornName(), Err: err}
+	}
+	name := instance.AcornName()
+	began := a.notifyBefore(phase, name)
+	nested := &nestedClock{}
+	err := a.invokeAcorn(ctx, phase, instance, nested)
+	a.recordTiming(phase, name, time.Since(began), nested.elapsed())
+	a.notifyAfter(phase, name, began, err)
+	return err
+}
+
+func (a *AcornRegistryImpl) invokeAcorn(ctx context.Context, phase Phase, instance auacornapi.Acorn, nested *nestedClock) error {
+	if a.acornTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, a.acornTimeout)
+		defer cancel()
+	}
+
+	scope := &acornScope{AcornRegistryImpl: a, acorn: instance, phase: phase, ctx: ctx, nested: nested}
+	contextAcorn, preferContext := instance.(auacornapi.ContextAcorn)
+	var err error
+	switch phase {
+	case PhaseAssembly:
+		if preferContext {
+			err = contextAcorn.AssembleAcornCtx(ctx, scope)
+		} else {
+			err = instance.AssembleAcorn(scope)
+		}
+	case PhaseSetup:
+		if preferContext {
+			err = contextAcorn.SetupAcornCtx(ctx, scope)
+		} else {
+			err = instance.SetupAcorn(scope)
+		}
+	case PhaseStart:
+		err = instance.(auacornapi.StartableAcorn).StartAcorn(scope)
+	case PhaseStop:
+		err = instance.(auacornapi.StoppableAcorn).StopAcorn(scope)
+	case PhaseTeardown:
+		if preferContext {
+			err = contextAcorn.TeardownAcornCtx(ctx, scope)
+		} else {
+			err = instance.TeardownAcorn(scope)
+		}
+	}
+
+	ctxErr := ctx.Err()
+	if ctxErr == nil {
+		return err
+	}
+	var other *TimeoutError
+	if err == nil || (errors.Is(err, ctxErr) && !errors.As(err, &other)) {
+		return &TimeoutError{Phase: phase, AcornName: instance.AcornName(), Err: ctxErr}
+	}
+	return err
+}
+
+// wrapLifecycleError attributes err to instance, unless it already is a TimeoutError of instance.
+func wrapLifecycleError(phase Phase, instance auacornapi.Acorn, err error) error {
+	if timeout, ok := err.(*TimeoutError); ok && timeout.AcornName == instance.AcornName() {
+		return err
+	}
+	return &LifecycleError{Phase: phase, AcornName: instance.AcornName(), Err: err}
+}
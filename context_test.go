@@ -0,0 +1,151 @@
+package auacorn
+
+import (
+	"context"
+	"errors"
+	auacornapi "github.com/StephanHCB/go-autumn-acorn-registry/api"
+	"github.com/StephanHCB/go-autumn-acorn-registry/test/ctxacorn"
+	"github.com/StephanHCB/go-autumn-acorn-registry/test/hook"
+	"github.com/StephanHCB/go-autumn-acorn-registry/test/mockc"
+	"github.com/StephanHCB/go-autumn-acorn-registry/test/para"
+	"github.com/StephanHCB/go-autumn-acorn-registry/test/rec"
+	"testing"
+	"time"
+)
+
+func TestContext_PrefersContextAcorn(t *testing.T) {
+	impl := New()
+	Registry = impl
+
+	Registry.Register(ctxacorn.New(&ctxacorn.CtxImpl{Name: "x"}))
+	Registry.Create()
+
+	rec.Reset()
+	if impl.AssembleCtx(context.Background()) != nil || impl.SetupCtx(context.Background()) != nil ||
+		impl.TeardownCtx(context.Background()) != nil {
+		t.FailNow()
+	}
+	assertRecording(t, []string{"x.AssembleAcornCtx", "x.SetupAcornCtx", "x.TeardownAcornCtx"})
+}
+
+func TestContext_AcornTimeout(t *testing.T) {
+	Registry = New(WithAcornTimeout(20 * time.Millisecond))
+
+	Registry.Register(ctxacorn.New(&ctxacorn.CtxImpl{Name: "x"}))
+	Registry.Register(ctxacorn.New(&ctxacorn.CtxImpl{Name: "y", SetupDelay: time.Minute}))
+	Registry.Create()
+	_ = Registry.Assemble()
+
+	rec.Reset()
+	err := Registry.Setup()
+	var timeoutErr *TimeoutError
+	if !errors.As(err, &timeoutErr) || timeoutErr.Phase != PhaseSetup || timeoutErr.AcornName != "y" {
+		t.FailNow()
+	}
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.FailNow()
+	}
+	if err.Error() != "setup of Acorn 'y' did not complete in time: context deadline exceeded" {
+		t.Errorf("unexpected error message: %s", err.Error())
+	}
+	assertRecording(t, []string{"x.SetupAcornCtx", "y.SetupCanceled"})
+}
+
+func TestContext_AcornTimeout_NotContextAware(t *testing.T) {
+	Registry = New(WithAcornTimeout(10 * time.Millisecond))
+
+	Registry.Register(para.New(&para.ParaImpl{Name: "x", Delay: 50 * time.Millisecond}))
+	Registry.Create()
+	_ = Registry.Assemble()
+
+	err := Registry.Setup()
+	var timeoutErr *TimeoutError
+	if !errors.As(err, &timeoutErr) || timeoutErr.AcornName != "x" {
+		t.FailNow()
+	}
+}
+
+func TestContext_PhaseTimeout(t *testing.T) {
+	Registry = New(WithPhaseTimeout(50 * time.Millisecond))
+
+	Registry.Register(ctxacorn.New(&ctxacorn.CtxImpl{Name: "x", SetupDelay: 20 * time.Millisecond}))
+	Registry.Register(ctxacorn.New(&ctxacorn.CtxImpl{Name: "y", SetupDelay: time.Minute}))
+	Registry.Register(ctxacorn.New(&ctxacorn.CtxImpl{Name: "z"}))
+	Registry.Create()
+	_ = Registry.Assemble()
+
+	rec.Reset()
+	err := Registry.Setup()
+	var timeoutErr *TimeoutError
+	if !errors.As(err, &timeoutErr) || timeoutErr.AcornName != "y" {
+		t.FailNow()
+	}
+	// z is never started
+	assertRecording(t, []string{"x.SetupAcornCtx", "y.SetupCanceled"})
+}
+
+func TestContext_Canceled(t *testing.T) {
+	impl := New()
+	Registry = impl
+
+	Registry.Register(ctxacorn.New(&ctxacorn.CtxImpl{Name: "x"}))
+	Registry.Create()
+	_ = Registry.Assemble()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	rec.Reset()
+	err := impl.SetupCtx(ctx)
+	var timeoutErr *TimeoutError
+	if !errors.As(err, &timeoutErr) || timeoutErr.AcornName != "x" || !errors.Is(err, context.Canceled) {
+		t.FailNow()
+	}
+	assertRecording(t, []string{})
+}
+
+func TestContext_TimeoutInSetupAfter(t *testing.T) {
+	Registry = New(WithAcornTimeout(20 * time.Millisecond))
+
+	Registry.Register(ctxacorn.New(&ctxacorn.CtxImpl{Name: "x"}))
+	Registry.Register(ctxacorn.New(&ctxacorn.CtxImpl{Name: "y", SetupDelay: time.Minute}))
+	Registry.Create()
+	// specifies: x is set up after y
+	_ = Registry.AddSetupOrderRule(Registry.GetAcornByName("y"), Registry.GetAcornByName("x"))
+	_ = Registry.Assemble()
+
+	err := Registry.Setup()
+	// y exceeded its deadline, which made x fail
+	var timeoutErr *TimeoutError
+	if !errors.As(err, &timeoutErr) || timeoutErr.AcornName != "y" {
+		t.FailNow()
+	}
+	var lifecycleErr *LifecycleError
+	if !errors.As(err, &lifecycleErr) || lifecycleErr.AcornName != "x" {
+		t.FailNow()
+	}
+}
+
+func TestContext_AcornUsesExtendedRegistry(t *testing.T) {
+	Registry = New()
+
+	var setupErr error
+	Registry.Register(hook.New(&hook.HookImpl{Name: "h", OnSetup: func(registry auacornapi.AcornRegistry) {
+		extended, ok := registry.(auacornapi.ExtendedAcornRegistry)
+		if !ok {
+			setupErr = errors.New("registry passed to SetupAcorn does not implement ExtendedAcornRegistry")
+			return
+		}
+		setupErr = extended.SetupAfterCtx(context.Background(), registry.GetAcornByName(mockc.MockCName))
+		rec.Add("h.SetupAcorn")
+	}}))
+	Registry.Register(mockc.New)
+	Registry.Create()
+	_ = Registry.Assemble()
+
+	rec.Reset()
+	if Registry.Setup() != nil || setupErr != nil {
+		t.FailNow()
+	}
+	assertRecording(t, []string{"c.SetupAcorn", "h.SetupAcorn"})
+}
@@ -0,0 +1,118 @@
+package auacorn
+
+import auacornapi "github.com/StephanHCB/go-autumn-acorn-registry/api"
+
+// declaration holds the dependency names an instance declared by implementing AcornWithDependencies.
+type declaration struct {
+	instance auacornapi.Acorn
+	setup    []string
+	teardown []string
+}
+
+// readDeclarations asks all instances that implement AcornWithDependencies for their dependencies.
+//
+// Must be called without holding the lock, as SetupDependencies and TeardownDependencies are implemented by Acorns.
+func (a *AcornRegistryImpl) readDeclarations() []declaration {
+	a.mu.Lock()
+	instances := make([]auacornapi.Acorn, 0, len(a.names))
+	for _, name := range a.orderedNames() {
+		instances = append(instances, a.instancesByName[name])
+	}
+	a.mu.Unlock()
+
+	result := make([]declaration, 0)
+	for _, instance := range instances {
+		if declaring, ok := instance.(auacornapi.AcornWithDependencies); ok {
+			result = append(result, declaration{
+				instance: instance,
+				setup:    declaring.SetupDependencies(),
+				teardown: declaring.TeardownDependencies(),
+			})
+		}
+	}
+	return result
+}
+
+// readDeclaredDependencies adds the declared dependencies to setupBefore and teardownBefore, replacing any
+// that an earlier, failed Assemble() added. Caller must hold the lock.
+//
+// Names that do not resolve are recorded as missing lookups of the declaring instance.
+func (a *AcornRegistryImpl) readDeclaredDependencies(declarations []declaration) {
+	for instance, edges := range a.setupBefore {
+		a.setupBefore[instance] = withoutEdges(edges, EdgeSetupDeclared)
+	}
+	for instance, edges := range a.teardownBefore {
+		a.teardownBefore[instance] = withoutEdges(edges, EdgeTeardownDeclared)
+	}
+
+	for _, declared := range declarations {
+		name := declared.instance.AcornName()
+		a.setupBefore[declared.instance] = append(a.setupBefore[declared.instance],
+			a.resolveDeclared(name, declared.setup, EdgeSetupDeclared)...)
+		a.teardownBefore[declared.instance] = append(a.teardownBefore[declared.instance],
+			a.resolveDeclared(name, declared.teardown, EdgeTeardownDeclared)...)
+	}
+}
+
+func withoutEdges(edges []edge, via EdgeKind) []edge {
+	result := make([]edge, 0, len(edges))
+	for _, entry := range edges {
+		if entry.via != via {
+			result = append(result, entry)
+		}
+	}
+	return result
+}
+
+func (a *AcornRegistryImpl) resolveDeclared(requester string, dependencyNames []string, via EdgeKind) []edge {
+	result := make([]edge, 0, len(dependencyNames))
+	for _, dependencyName := range dependencyNames {
+		a.observeEdge(requester, dependencyName, via)
+		dependency, ok := a.instancesByName[dependencyName]
+		if !ok {
+			a.missingLookups = append(a.missingLookups, &MissingAcornError{Requester: requester, AcornName: dependencyName})
+			continue
+		}
+		result = append(result, edge{instance: dependency, via: via})
+	}
+	return result
+}
+
+// findCycle searches the dependencies known before running any setup or teardown, that is order rules
+// and declared dependencies, and returns the first cycle found, or nil.
+func (a *AcornRegistryImpl) findCycle(phase Phase, before map[auacornapi.Acorn][]edge) *CycleError {
+	const (
+		unvisited = iota
+		inProgress
+		done
+	)
+	state := make(map[auacornapi.Acorn]int)
+	stack := make([]edge, 0)
+
+	var visit func(current edge) *CycleError
+	visit = func(current edge) *CycleError {
+		switch state[current.instance] {
+		case inProgress:
+			return cycleError(phase, stack, current.instance, current.via)
+		case done:
+			return nil
+		}
+		state[current.instance] = inProgress
+		stack = append(stack, current)
+		for _, prerequisite := range before[current.instance] {
+			if err := visit(prerequisite); err != nil {
+				return err
+			}
+		}
+		stack = stack[:len(stack)-1]
+		state[current.instance] = done
+		return nil
+	}
+
+	for _, name := range a.orderedNames() {
+		if err := visit(edge{instance: a.instancesByName[name]}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
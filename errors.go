@@ -0,0 +1,173 @@
+package auacorn
+
+import "fmt"
+
+// Phase names a lifecycle phase of the registry and its Acorns.
+type Phase string
+
+const (
+	PhaseCreation Phase = "creation"
+	PhaseAssembly Phase = "assembly"
+	PhaseSetup    Phase = "setup"
+	PhaseStart    Phase = "start"
+	PhaseStop     Phase = "stop"
+	PhaseTeardown Phase = "teardown"
+)
+
+// LifecycleError reports that an Acorn's AssembleAcorn, SetupAcorn, StartAcorn, StopAcorn or TeardownAcorn
+// returned an error.
+//
+// Use errors.Is or errors.As to inspect the original error.
+type LifecycleError struct {
+	Phase     Phase
+	AcornName string
+	Err       error
+}
+
+func (e *LifecycleError) Error() string {
+	return fmt.Sprintf("error during %s of Acorn '%s': %s", e.Phase, e.AcornName, e.Err.Error())
+}
+
+func (e *LifecycleError) Unwrap() error {
+	return e.Err
+}
+
+// TimeoutError reports that an Acorn's context ran out before its AssembleAcorn, SetupAcorn, StartAcorn,
+// StopAcorn or TeardownAcorn completed, either because its deadline was exceeded or because it was canceled.
+//
+// An Acorn that finishes late is still reported, even if it does not look at its context. If the context was
+// already done, the registry does not call the Acorn at all, and reports it as well.
+type TimeoutError struct {
+	Phase     Phase
+	AcornName string
+	Err       error // the context's error, context.DeadlineExceeded or context.Canceled
+}
+
+func (e *TimeoutError) Error() string {
+	return fmt.Sprintf("%s of Acorn '%s' did not complete in time: %s", e.Phase, e.AcornName, e.Err.Error())
+}
+
+func (e *TimeoutError) Unwrap() error {
+	return e.Err
+}
+
+// EdgeKind tells how a dependency between two Acorns was specified.
+type EdgeKind string
+
+const (
+	EdgeSetupAfter     EdgeKind = "SetupAfter"
+	EdgeSetupOrderRule EdgeKind = "AddSetupOrderRule"
+	EdgeSetupDeclared  EdgeKind = "SetupDependencies"
+
+	EdgeTeardownAfter     EdgeKind = "TeardownAfter"
+	EdgeTeardownOrderRule EdgeKind = "AddTeardownOrderRule"
+	EdgeTeardownDeclared  EdgeKind = "TeardownDependencies"
+
+	// EdgeLookup is an Acorn looking up another one during assembly. It does not order anything by itself,
+	// and only shows up in the DependencyGraph.
+	EdgeLookup EdgeKind = "GetAcornByName"
+)
+
+// CycleStep is one Acorn on the path of a circular dependency.
+type CycleStep struct {
+	AcornName string
+	Via       EdgeKind // how the previous Acorn on the path came to wait for this one, empty for the first step
+}
+
+// CycleError reports a circular setup or teardown dependency.
+type CycleError struct {
+	Phase     Phase
+	AcornName string      // the Acorn that was requested while its own setup or teardown was still in progress
+	Path      []CycleStep // the complete cycle, starting and ending with AcornName
+}
+
+func (e *CycleError) Error() string {
+	if len(e.Path) == 0 {
+		return fmt.Sprintf("circular %s dependency involving Acorn %s - not allowed", e.Phase, e.AcornName)
+	}
+	path := ""
+	for _, step := range e.Path {
+		if step.Via != "" {
+			path += fmt.Sprintf(" -[%s]-> ", step.Via)
+		}
+		path += step.AcornName
+	}
+	return fmt.Sprintf("circular %s dependency %s - not allowed", e.Phase, path)
+}
+
+// UnscopedCallError reports a call to SetupAfter or TeardownAfter that was not made on the registry passed to
+// the calling Acorn, but e.g. on the global Registry. The registry then cannot tell which Acorn is waiting,
+// so it could neither detect circular dependencies nor let several goroutines of one Acorn wait.
+type UnscopedCallError struct {
+	Method string // name of the registry method that was called, e.g. "SetupAfter"
+}
+
+func (e *UnscopedCallError) Error() string {
+	return fmt.Sprintf("%s() must be called on the registry passed to the Acorn, so the registry knows who is waiting", e.Method)
+}
+
+// PhaseOrderError reports that a registry method was called in the wrong registry phase.
+type PhaseOrderError struct {
+	Method string // name of the registry method that was called, e.g. "Setup"
+	Detail string // when the method may be called
+}
+
+func (e *PhaseOrderError) Error() string {
+	return fmt.Sprintf("wrong acorn registry phase for call to %s() - %s", e.Method, e.Detail)
+}
+
+// MissingAcornError reports that an Acorn looked up an unknown Acorn name during assembly.
+//
+// Assemble() returns all of them joined into one error.
+type MissingAcornError struct {
+	Requester string // name of the Acorn that made the lookup
+	AcornName string // the unknown name it looked up
+}
+
+func (e *MissingAcornError) Error() string {
+	return fmt.Sprintf("acorn '%s' requested unknown acorn '%s'", e.Requester, e.AcornName)
+}
+
+// LazyAcornNameError reports that the constructor passed to RegisterLazy() created an Acorn whose AcornName()
+// differs from the name it was registered for. The Acorn is not used.
+//
+// Assemble() returns it together with the lookups that could not be resolved because of it.
+type LazyAcornNameError struct {
+	RegisteredName string // the name passed to RegisterLazy()
+	AcornName      string // the AcornName() of the created Acorn
+}
+
+func (e *LazyAcornNameError) Error() string {
+	return fmt.Sprintf("lazy acorn registered as '%s' was created with name '%s'", e.RegisteredName, e.AcornName)
+}
+
+// OverriddenAcornError reports an order rule or declared dependency that refers to an instance which was
+// replaced using CreateOverride() afterwards, so the registry no longer knows it.
+//
+// Validate() returns these.
+type OverriddenAcornError struct {
+	AcornName string   // the Acorn whose instance was replaced
+	From      string   // the dependent Acorn
+	To        string   // the Acorn it depends on
+	Via       EdgeKind // how the dependency was specified
+}
+
+func (e *OverriddenAcornError) Error() string {
+	return fmt.Sprintf("dependency %s -[%s]-> %s refers to an instance of acorn '%s' that was replaced by CreateOverride()",
+		e.From, e.Via, e.To, e.AcornName)
+}
+
+// DuplicateAcornError reports that two constructors registered with Register() produced Acorns with the same name.
+//
+// Depending on the DuplicatePolicy, it is passed to the warning handler or returned from Create(), joined with
+// any other duplicates.
+type DuplicateAcornError struct {
+	AcornName     string
+	ReplacedType  string // concrete type of the Acorn that was replaced
+	ReplacingType string // concrete type of the Acorn that replaced it
+}
+
+func (e *DuplicateAcornError) Error() string {
+	return fmt.Sprintf("duplicate acorn name '%s': %s replaces %s - use RegisterOverride() if this is intended",
+		e.AcornName, e.ReplacingType, e.ReplacedType)
+}
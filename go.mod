@@ -1,3 +1,3 @@
 module github.com/StephanHCB/go-autumn-acorn-registry
 
-go 1.17
+go 1.20
@@ -0,0 +1,158 @@
+package auacorn
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// GraphSchemaVersion is the version of the JSON format written by DependencyGraph.JSON(). It only changes
+// if the format changes in a way that breaks existing readers.
+const GraphSchemaVersion = 1
+
+// GraphAcorn is a node of the DependencyGraph.
+type GraphAcorn struct {
+	Name string `json:"name"`
+
+	// Type is the Go type of the instance, empty if Missing.
+	Type string `json:"type,omitempty"`
+
+	// Missing is set for names that some Acorn referred to, but that no Acorn has.
+	Missing bool `json:"missing,omitempty"`
+}
+
+// GraphEdge is an edge of the DependencyGraph. From depends on To in the way Kind says, that is, From looked up To,
+// or From is set up after To, or From is torn down after To.
+type GraphEdge struct {
+	From string   `json:"from"`
+	To   string   `json:"to"`
+	Kind EdgeKind `json:"kind"`
+}
+
+// DependencyGraph is every dependency between Acorns the registry has observed so far.
+//
+// Acorns and edges are sorted by name, so the output is stable between runs, no matter in which order
+// dependencies were observed.
+type DependencyGraph struct {
+	Version int          `json:"version"`
+	Acorns  []GraphAcorn `json:"acorns"`
+	Edges   []GraphEdge  `json:"edges"`
+}
+
+// DependencyGraph returns every dependency observed so far. Call it after Assemble() for the lookups,
+// order rules and declared dependencies, and after Setup() and Teardown() to also see the SetupAfter()
+// and TeardownAfter() calls.
+func (a *AcornRegistryImpl) DependencyGraph() DependencyGraph {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	graph := DependencyGraph{
+		Version: GraphSchemaVersion,
+		Acorns:  make([]GraphAcorn, 0, len(a.names)),
+		Edges:   make([]GraphEdge, len(a.observedEdges)),
+	}
+	known := make(map[string]bool)
+	for _, name := range a.names {
+		graph.Acorns = append(graph.Acorns, GraphAcorn{Name: name, Type: fmt.Sprintf("%T", a.instancesByName[name])})
+		known[name] = true
+	}
+	copy(graph.Edges, a.observedEdges)
+	for _, observed := range graph.Edges {
+		for _, name := range []string{observed.From, observed.To} {
+			if !known[name] {
+				graph.Acorns = append(graph.Acorns, GraphAcorn{Name: name, Missing: true})
+				known[name] = true
+			}
+		}
+	}
+
+	sort.Slice(graph.Acorns, func(i, j int) bool {
+		return graph.Acorns[i].Name < graph.Acorns[j].Name
+	})
+	sort.Slice(graph.Edges, func(i, j int) bool {
+		if graph.Edges[i].From != graph.Edges[j].From {
+			return graph.Edges[i].From < graph.Edges[j].From
+		}
+		if graph.Edges[i].To != graph.Edges[j].To {
+			return graph.Edges[i].To < graph.Edges[j].To
+		}
+		return graph.Edges[i].Kind < graph.Edges[j].Kind
+	})
+	return graph
+}
+
+// observeEdge adds an edge to the DependencyGraph, unless it is already known. Caller must hold the lock.
+func (a *AcornRegistryImpl) observeEdge(from string, to string, kind EdgeKind) {
+	observed := GraphEdge{From: from, To: to, Kind: kind}
+	if a.observedEdgeSet[observed] {
+		return
+	}
+	a.observedEdgeSet[observed] = true
+	a.observedEdges = append(a.observedEdges, observed)
+}
+
+// JSON renders the graph in a stable JSON format, see GraphSchemaVersion.
+func (g DependencyGraph) JSON() ([]byte, error) {
+	return json.MarshalIndent(g, "", "  ")
+}
+
+// DOT renders the graph for Graphviz.
+//
+// Lookups are dotted, teardown edges are dashed, and missing Acorns are drawn with a dashed outline.
+func (g DependencyGraph) DOT() string {
+	var builder strings.Builder
+	builder.WriteString("digraph acorns {\n")
+	for _, acorn := range g.Acorns {
+		if acorn.Missing {
+			builder.WriteString(fmt.Sprintf("  %q [style=dashed];\n", acorn.Name))
+		} else {
+			builder.WriteString(fmt.Sprintf("  %q;\n", acorn.Name))
+		}
+	}
+	for _, observed := range g.Edges {
+		attributes := fmt.Sprintf("label=%q", string(observed.Kind))
+		switch {
+		case observed.Kind == EdgeLookup:
+			attributes += ", style=dotted"
+		case isTeardownEdge(observed.Kind):
+			attributes += ", style=dashed"
+		}
+		builder.WriteString(fmt.Sprintf("  %q -> %q [%s];\n", observed.From, observed.To, attributes))
+	}
+	builder.WriteString("}\n")
+	return builder.String()
+}
+
+// Mermaid renders the graph as a Mermaid flowchart.
+//
+// Lookups are dotted, teardown edges are thick, and missing Acorns are marked as such.
+func (g DependencyGraph) Mermaid() string {
+	ids := make(map[string]string)
+	var builder strings.Builder
+	builder.WriteString("graph LR\n")
+	for i, acorn := range g.Acorns {
+		id := fmt.Sprintf("n%d", i)
+		ids[acorn.Name] = id
+		label := acorn.Name
+		if acorn.Missing {
+			label += " (missing)"
+		}
+		builder.WriteString(fmt.Sprintf("  %s[\"%s\"]\n", id, strings.ReplaceAll(label, `"`, "#quot;")))
+	}
+	for _, observed := range g.Edges {
+		arrow := "-->"
+		switch {
+		case observed.Kind == EdgeLookup:
+			arrow = "-.->"
+		case isTeardownEdge(observed.Kind):
+			arrow = "==>"
+		}
+		builder.WriteString(fmt.Sprintf("  %s %s|%s| %s\n", ids[observed.From], arrow, observed.Kind, ids[observed.To]))
+	}
+	return builder.String()
+}
+
+func isTeardownEdge(kind EdgeKind) bool {
+	return kind == EdgeTeardownAfter || kind == EdgeTeardownOrderRule || kind == EdgeTeardownDeclared
+}
@@ -0,0 +1,119 @@
+package auacorn
+
+import (
+	"github.com/StephanHCB/go-autumn-acorn-registry/test/para"
+	"testing"
+)
+
+func setupGraphScenario() *AcornRegistryImpl {
+	impl := New()
+	Registry = impl
+
+	Registry.Register(para.New(&para.ParaImpl{Name: "p1", SetupAfterNames: []string{"p2"}, TeardownAfterNames: []string{"p3"}}))
+	Registry.Register(para.New(&para.ParaImpl{Name: "p2"}))
+	Registry.Register(para.New(&para.ParaImpl{Name: "p3", SetupDeps: []string{"p2"}}))
+	Registry.Create()
+	_ = Registry.Assemble()
+	return impl
+}
+
+func TestGraph_JSON(t *testing.T) {
+	impl := setupGraphScenario()
+	_ = Registry.Setup()
+	_ = Registry.Teardown()
+
+	actual, err := impl.DependencyGraph().JSON()
+	if err != nil {
+		t.FailNow()
+	}
+	expected := `{
+  "version": 1,
+  "acorns": [
+    {
+      "name": "p1",
+      "type": "*para.ParaImpl"
+    },
+    {
+      "name": "p2",
+      "type": "*para.ParaImpl"
+    },
+    {
+      "name": "p3",
+      "type": "*para.ParaImpl"
+    }
+  ],
+  "edges": [
+    {
+      "from": "p1",
+      "to": "p2",
+      "kind": "GetAcornByName"
+    },
+    {
+      "from": "p1",
+      "to": "p2",
+      "kind": "SetupAfter"
+    },
+    {
+      "from": "p1",
+      "to": "p3",
+      "kind": "GetAcornByName"
+    },
+    {
+      "from": "p1",
+      "to": "p3",
+      "kind": "TeardownAfter"
+    },
+    {
+      "from": "p3",
+      "to": "p2",
+      "kind": "SetupDependencies"
+    }
+  ]
+}`
+	if string(actual) != expected {
+		t.Errorf("unexpected json: %s", string(actual))
+	}
+}
+
+func TestGraph_DOT(t *testing.T) {
+	impl := setupGraphScenario()
+	_ = impl.AddTeardownOrderRule(impl.GetAcornByName("p2"), impl.GetAcornByName("p3"))
+
+	expected := `digraph acorns {
+  "p1";
+  "p2";
+  "p3";
+  "p1" -> "p2" [label="GetAcornByName", style=dotted];
+  "p1" -> "p3" [label="GetAcornByName", style=dotted];
+  "p3" -> "p2" [label="SetupDependencies"];
+}
+`
+	// the order rule came too late, Assemble() was already done
+	if actual := impl.DependencyGraph().DOT(); actual != expected {
+		t.Errorf("unexpected dot: %s", actual)
+	}
+}
+
+func TestGraph_MermaidWithMissingAcorn(t *testing.T) {
+	impl := New()
+	Registry = impl
+
+	Registry.Register(para.New(&para.ParaImpl{Name: "p1", SetupDeps: []string{"ghost"}}))
+	Registry.Register(para.New(&para.ParaImpl{Name: "p2"}))
+	Registry.Create()
+	_ = impl.AddTeardownOrderRule(impl.GetAcornByName("p1"), impl.GetAcornByName("p2"))
+	if Registry.Assemble() == nil {
+		t.FailNow()
+	}
+
+	expected := `graph LR
+  n0["ghost (missing)"]
+  n1["p1"]
+  n2["p2"]
+  n1 -->|SetupDependencies| n0
+  n2 ==>|AddTeardownOrderRule| n1
+`
+	if actual := impl.DependencyGraph().Mermaid(); actual != expected {
+		t.Errorf("unexpected mermaid: %s", actual)
+	}
+}
@@ -0,0 +1,120 @@
+package auacorn
+
+import (
+	"fmt"
+	auacornapi "github.com/StephanHCB/go-autumn-acorn-registry/api"
+)
+
+// GuardMode determines what a guard proxy does when a method of an Acorn is called at the wrong time.
+type GuardMode uint8
+
+const (
+	// GuardRecord records each UnreadyAcornError, see Violations().
+	GuardRecord GuardMode = iota
+
+	// GuardPanic records each UnreadyAcornError, and then panics with it, so you get a stack trace.
+	GuardPanic
+)
+
+// WithDependencyGuards makes GetAcornByName hand out guard proxies to Acorns, for all Acorns that have a
+// GuardAdapter registered with RegisterGuardAdapter(). Acorns without one are handed out as before.
+//
+// The proxies detect methods called on an Acorn before it is set up, which the documentation of GetAcornByName
+// warns against, or after it was torn down.
+//
+// This is meant for debugging and tests, as each call through a proxy has to take the registry's lock.
+func WithDependencyGuards(mode GuardMode) Option {
+	return func(registry *AcornRegistryImpl) {
+		registry.guards = true
+		registry.guardMode = mode
+	}
+}
+
+// UnreadyAcornError reports a method called on an Acorn that was not set up, detected by a guard proxy.
+type UnreadyAcornError struct {
+	Requester string // the Acorn the proxy was handed out to
+	AcornName string // the guarded Acorn
+	Method    string // the method that was called
+	When      string // e.g. "before it was set up"
+}
+
+func (e *UnreadyAcornError) Error() string {
+	return fmt.Sprintf("acorn %s called %s on acorn %s %s", e.Requester, e.Method, e.AcornName, e.When)
+}
+
+func (a *AcornRegistryImpl) RegisterGuardAdapter(acornName string, adapter auacornapi.GuardAdapter) {
+	_ = a.violation("RegisterGuardAdapter")
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.guardAdapters[acornName] = adapter
+}
+
+// guard is the AcornGuard passed to GuardAdapters, one per lookup.
+type guard struct {
+	auacornapi.Acorn // the guarded instance
+	registry         *AcornRegistryImpl
+	requester        string
+}
+
+func (g *guard) Check(method string) {
+	g.registry.checkGuarded(g, method)
+}
+
+func (g *guard) Target() auacornapi.Acorn {
+	return g.Acorn
+}
+
+// guarded returns a guard proxy for instance, if guards are on and instance has a GuardAdapter,
+// or else instance itself.
+func (a *AcornRegistryImpl) guarded(requester auacornapi.Acorn, instance auacornapi.Acorn) auacornapi.Acorn {
+	if !a.guards || instance == nil {
+		return instance
+	}
+	a.mu.Lock()
+	adapter, ok := a.guardAdapters[instance.AcornName()]
+	requesterName := requester.AcornName()
+	a.mu.Unlock()
+	if !ok {
+		return instance
+	}
+
+	// the adapter is called without holding the lock, like any other code provided by Acorns
+	proxy := adapter(&guard{Acorn: instance, registry: a, requester: requesterName})
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.guardTargets[proxy] = instance
+	return proxy
+}
+
+// unguarded returns the instance behind a guard proxy, or instance itself if it is not a proxy.
+// Caller must hold the lock.
+func (a *AcornRegistryImpl) unguarded(instance auacornapi.Acorn) auacornapi.Acorn {
+	if target, ok := a.guardTargets[instance]; ok {
+		return target
+	}
+	return instance
+}
+
+func (a *AcornRegistryImpl) checkGuarded(g *guard, method string) {
+	a.mu.Lock()
+	when := ""
+	switch a.phaseByInstance[g.Acorn] {
+	case phaseSetupDone:
+		a.mu.Unlock()
+		return
+	case phaseInRecursiveTeardown:
+		when = "while it was being torn down"
+	case phaseTeardownDone:
+		when = "after it was torn down"
+	default:
+		when = "before it was set up"
+	}
+	violation := &UnreadyAcornError{Requester: g.requester, AcornName: g.AcornName(), Method: method, When: when}
+	a.violations = append(a.violations, violation)
+	a.mu.Unlock()
+
+	if a.guardMode == GuardPanic {
+		panic(violation)
+	}
+}
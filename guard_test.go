@@ -0,0 +1,78 @@
+package auacorn
+
+import (
+	"errors"
+	auacornapi "github.com/StephanHCB/go-autumn-acorn-registry/api"
+	"github.com/StephanHCB/go-autumn-acorn-registry/test/hook"
+	"github.com/StephanHCB/go-autumn-acorn-registry/test/mockc"
+	"github.com/StephanHCB/go-autumn-acorn-registry/test/rec"
+	"testing"
+)
+
+func registerGuardScenario(impl *AcornRegistryImpl) {
+	var c mockc.MockC
+	impl.Register(hook.New(&hook.HookImpl{
+		Name: "h",
+		OnAssemble: func(registry auacornapi.AcornRegistry) {
+			c = auacornapi.MustGet[mockc.MockC](registry, nil, mockc.MockCName)
+			c.IsC()
+		},
+		OnSetup: func(registry auacornapi.AcornRegistry) {
+			c.IsC()
+			_ = registry.SetupAfter(c.(auacornapi.Acorn))
+			c.IsC()
+		},
+		OnTeardown: func(registry auacornapi.AcornRegistry) {
+			_ = registry.TeardownAfter(c.(auacornapi.Acorn))
+			c.IsC()
+		},
+	}))
+	impl.Register(mockc.New)
+	impl.RegisterGuardAdapter(mockc.MockCName, mockc.GuardAdapter)
+}
+
+func TestGuards_Record(t *testing.T) {
+	impl := New(WithDependencyGuards(GuardRecord))
+	Registry = impl
+	registerGuardScenario(impl)
+	Registry.Create()
+
+	rec.Reset()
+	if Registry.Assemble() != nil || Registry.Setup() != nil || Registry.Teardown() != nil {
+		t.FailNow()
+	}
+	// the proxy is recognized by SetupAfter and TeardownAfter, so mockc is only set up and torn down once
+	assertRecording(t, []string{"c.AssembleAcorn", "c.SetupAcorn", "c.TeardownAcorn"})
+	assertViolations(t, impl, []string{
+		"acorn h called IsC on acorn mockc before it was set up",
+		"acorn h called IsC on acorn mockc before it was set up",
+		"acorn h called IsC on acorn mockc after it was torn down",
+	})
+}
+
+func TestGuards_Panic(t *testing.T) {
+	impl := New(WithDependencyGuards(GuardPanic))
+	Registry = impl
+	registerGuardScenario(impl)
+	Registry.Create()
+
+	defer func() {
+		var violation *UnreadyAcornError
+		if err, ok := recover().(error); !ok || !errors.As(err, &violation) || violation.Method != "IsC" {
+			t.Errorf("expected a panic with an UnreadyAcornError")
+		}
+	}()
+	_ = Registry.Assemble()
+}
+
+func TestGuards_Off(t *testing.T) {
+	impl := New()
+	Registry = impl
+	registerGuardScenario(impl)
+	Registry.Create()
+
+	if Registry.Assemble() != nil || Registry.Setup() != nil || Registry.Teardown() != nil {
+		t.FailNow()
+	}
+	assertViolations(t, impl, []string{})
+}
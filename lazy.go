@@ -0,0 +1,112 @@
+package auacorn
+
+import (
+	auacornapi "github.com/StephanHCB/go-autumn-acorn-registry/api"
+	"sync"
+)
+
+// lazyAcorn is an Acorn registered with RegisterLazy(), which is only constructed once it is looked up.
+type lazyAcorn struct {
+	constructor auacornapi.Constructor
+	once        sync.Once
+	instance    auacornapi.Acorn
+}
+
+// RegisterLazy registers the constructor of an Acorn that is only created once another Acorn looks it up
+// during assembly, or declares a dependency on it.
+//
+// acornName must be the AcornName() of the Acorn the constructor creates, as the registry needs to know it
+// before calling the constructor, or else Assemble() fails with a LazyAcornNameError. If an Acorn of the same
+// name is registered with Register(), that one wins.
+//
+// Once created, the Acorn is assembled right away in the same Assemble(). It is set up when an Acorn first calls
+// SetupAfter() for it, or at the end of Setup() after all other Acorns, and is torn down like all others.
+// Lazy Acorns that are never looked up are never created, set up or torn down. Lookups after Assemble()
+// do not create lazy Acorns anymore.
+func (a *AcornRegistryImpl) RegisterLazy(acornName string, constructor auacornapi.Constructor) {
+	_ = a.violation("RegisterLazy")
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.lazyAcorns[acornName] = &lazyAcorn{constructor: constructor}
+}
+
+// wantsLazy tells whether acornName is a lazy Acorn that can and needs to be created now.
+// Caller must hold the lock.
+func (a *AcornRegistryImpl) wantsLazy(acornName string) bool {
+	if _, ok := a.instancesByName[acornName]; ok {
+		return false
+	}
+	return a.phase == phaseCreateDone && a.lazyAcorns[acornName] != nil
+}
+
+// instantiateLazy creates the lazy Acorn registered for acornName, unless it was already created. The constructor
+// is called exactly once, even if several goroutines look up the Acorn at the same time.
+//
+// If the created Acorn has a different name, it is recorded as a LazyAcornNameError, so Assemble() fails.
+func (a *AcornRegistryImpl) instantiateLazy(acornName string) {
+	a.mu.Lock()
+	lazy := a.lazyAcorns[acornName]
+	a.mu.Unlock()
+
+	// the constructor is called without holding the lock, like all others
+	lazy.once.Do(func() {
+		lazy.instance = lazy.constructor()
+	})
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if name := lazy.instance.AcornName(); name != acornName {
+		mismatch := LazyAcornNameError{RegisteredName: acornName, AcornName: name}
+		for _, known := range a.missingLookups {
+			if recorded, ok := known.(*LazyAcornNameError); ok && *recorded == mismatch {
+				return
+			}
+		}
+		a.missingLookups = append(a.missingLookups, &mismatch)
+		return
+	}
+	if _, ok := a.instancesByName[acornName]; !ok {
+		a.putInstance(acornName, lazy.instance)
+		a.lazyInstances[lazy.instance] = true
+	}
+}
+
+// instantiateDeclaredLazy creates the lazy Acorns that instance declares dependencies on, so they are
+// assembled in the same Assemble().
+func (a *AcornRegistryImpl) instantiateDeclaredLazy(instance auacornapi.Acorn) {
+	declaring, ok := instance.(auacornapi.AcornWithDependencies)
+	if !ok {
+		return
+	}
+	declared := [][]string{declaring.SetupDependencies(), declaring.TeardownDependencies()}
+
+	a.mu.Lock()
+	wanted := make([]string, 0)
+	for _, names := range declared {
+		for _, name := range names {
+			if a.wantsLazy(name) {
+				wanted = append(wanted, name)
+			}
+		}
+	}
+	a.mu.Unlock()
+
+	for _, name := range wanted {
+		a.instantiateLazy(name)
+	}
+}
+
+// eagerFirst moves the lazy Acorns among instances to the end, keeping the order otherwise.
+// Caller must hold the lock.
+func (a *AcornRegistryImpl) eagerFirst(instances []auacornapi.Acorn) []auacornapi.Acorn {
+	result := make([]auacornapi.Acorn, 0, len(instances))
+	lazy := make([]auacornapi.Acorn, 0)
+	for _, instance := range instances {
+		if a.lazyInstances[instance] {
+			lazy = append(lazy, instance)
+		} else {
+			result = append(result, instance)
+		}
+	}
+	return append(result, lazy...)
+}
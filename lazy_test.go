@@ -0,0 +1,126 @@
+package auacorn
+
+import (
+	"errors"
+	auacornapi "github.com/StephanHCB/go-autumn-acorn-registry/api"
+	"github.com/StephanHCB/go-autumn-acorn-registry/test/hook"
+	"github.com/StephanHCB/go-autumn-acorn-registry/test/mockc"
+	"github.com/StephanHCB/go-autumn-acorn-registry/test/para"
+	"github.com/StephanHCB/go-autumn-acorn-registry/test/rec"
+	"testing"
+)
+
+func recordingConstructor(impl *para.ParaImpl) auacornapi.Constructor {
+	return func() auacornapi.Acorn {
+		rec.Add(impl.Name + ".New")
+		return impl
+	}
+}
+
+func TestLazy_OnlyReferencedAcornsAreCreated(t *testing.T) {
+	impl := New()
+	Registry = impl
+
+	Registry.Register(para.New(&para.ParaImpl{Name: "p1", SetupAfterNames: []string{"l1"}}))
+	Registry.Register(para.New(&para.ParaImpl{Name: "p2", SetupDeps: []string{"l3"}}))
+	Registry.Register(para.New(&para.ParaImpl{Name: "p3", TeardownAfterNames: []string{"l5"}}))
+	impl.RegisterLazy("l1", recordingConstructor(&para.ParaImpl{Name: "l1", SetupAfterNames: []string{"l4"}}))
+	impl.RegisterLazy("l2", recordingConstructor(&para.ParaImpl{Name: "l2"}))
+	impl.RegisterLazy("l3", recordingConstructor(&para.ParaImpl{Name: "l3"}))
+	impl.RegisterLazy("l4", recordingConstructor(&para.ParaImpl{Name: "l4"}))
+	impl.RegisterLazy("l5", recordingConstructor(&para.ParaImpl{Name: "l5"}))
+
+	rec.Reset()
+	if Registry.Create() != nil {
+		t.FailNow()
+	}
+	assertRecording(t, []string{})
+
+	if Registry.Assemble() != nil {
+		t.FailNow()
+	}
+	// l4 is only looked up by l1, so it is created when l1 is assembled
+	assertRecording(t, []string{"l1.New", "l3.New", "l5.New", "l4.New"})
+
+	rec.Reset()
+	if Registry.Setup() != nil {
+		t.FailNow()
+	}
+	// l5 is looked up, but nobody asks for it to be set up, so that happens last
+	assertRecording(t, []string{
+		"p1.PreSetupAcorn", "l1.PreSetupAcorn", "l4.PreSetupAcorn", "l4.SetupAcorn", "l1.SetupAcorn", "p1.SetupAcorn",
+		"l3.PreSetupAcorn", "l3.SetupAcorn", "p2.PreSetupAcorn", "p2.SetupAcorn",
+		"p3.PreSetupAcorn", "p3.SetupAcorn",
+		"l5.PreSetupAcorn", "l5.SetupAcorn",
+	})
+
+	rec.Reset()
+	if Registry.Teardown() != nil {
+		t.FailNow()
+	}
+	assertRecording(t, []string{
+		"l5.PreTeardownAcorn", "l5.TeardownAcorn",
+		"p3.PreTeardownAcorn", "p3.TeardownAcorn",
+		"p2.PreTeardownAcorn", "p2.TeardownAcorn",
+		"l3.PreTeardownAcorn", "l3.TeardownAcorn",
+		"p1.PreTeardownAcorn", "p1.TeardownAcorn",
+		"l1.PreTeardownAcorn", "l1.TeardownAcorn",
+		"l4.PreTeardownAcorn", "l4.TeardownAcorn",
+	})
+	if Registry.GetAcornByName("l2") != nil {
+		t.FailNow()
+	}
+}
+
+func TestLazy_ParallelSetup(t *testing.T) {
+	impl := New(WithParallelSetup(0))
+	Registry = impl
+
+	Registry.Register(para.New(&para.ParaImpl{Name: "p1", SetupAfterNames: []string{"l1"}}))
+	Registry.Register(para.New(&para.ParaImpl{Name: "p2", SetupAfterNames: []string{"l2"}}))
+	impl.RegisterLazy("l1", recordingConstructor(&para.ParaImpl{Name: "l1"}))
+	impl.RegisterLazy("l2", recordingConstructor(&para.ParaImpl{Name: "l2"}))
+	impl.RegisterLazy("l3", recordingConstructor(&para.ParaImpl{Name: "l3"}))
+	Registry.Create()
+	if Registry.Assemble() != nil {
+		t.FailNow()
+	}
+
+	rec.Reset()
+	if Registry.Setup() != nil {
+		t.FailNow()
+	}
+	// each lazy acorn is set up by the acorn that asked for it
+	if indexRecorded("l1.SetupAcorn") > indexRecorded("p1.SetupAcorn") || indexRecorded("l2.SetupAcorn") > indexRecorded("p2.SetupAcorn") {
+		t.Errorf("unexpected recording: %v", rec.Get())
+	}
+	if countRecorded("l1.SetupAcorn") != 1 || countRecorded("l2.SetupAcorn") != 1 || countRecorded("l3.PreSetupAcorn") != 0 {
+		t.Errorf("unexpected recording: %v", rec.Get())
+	}
+}
+
+func TestLazy_WrongName(t *testing.T) {
+	impl := New()
+	Registry = impl
+
+	Registry.Register(hook.New(&hook.HookImpl{Name: "hooked", OnAssemble: func(registry auacornapi.AcornRegistry) {
+		_ = registry.GetAcornByName("lazyc")
+		_ = registry.GetAcornByName("lazyc")
+	}}))
+	impl.RegisterLazy("lazyc", mockc.New)
+	Registry.Create()
+
+	err := Registry.Assemble()
+	var nameErr *LazyAcornNameError
+	if !errors.As(err, &nameErr) || nameErr.RegisteredName != "lazyc" || nameErr.AcornName != mockc.MockCName {
+		t.FailNow()
+	}
+	expected := "lazy acorn registered as 'lazyc' was created with name 'mockc'\n" +
+		"acorn 'hooked' requested unknown acorn 'lazyc'"
+	if err.Error() != expected {
+		t.Errorf("unexpected error message: %s", err.Error())
+	}
+	if Registry.GetAcornByName(mockc.MockCName) != nil {
+		t.FailNow()
+	}
+}
@@ -0,0 +1,57 @@
+package auacorn
+
+import (
+	"time"
+)
+
+// Listener is notified around each call of an Acorn's lifecycle methods, and around each registry phase.
+//
+// For registry phases, acornName is empty. Calls for single Acorns nest inside the calls for their phase,
+// and calls for Acorns set up or torn down through SetupAfter() or TeardownAfter() nest inside the calls
+// for the Acorn that asked for them.
+//
+// Listeners are called without holding any locks, but with WithParallelSetup() or WithParallelTeardown(),
+// or if Acorns spawn their own goroutines, they may be called from several goroutines at the same time.
+type Listener interface {
+	// OnBefore is called right before phase begins for acornName.
+	OnBefore(phase Phase, acornName string)
+
+	// OnAfter is called once phase has ended for acornName, with the time it took and its outcome.
+	OnAfter(phase Phase, acornName string, duration time.Duration, err error)
+}
+
+// WithListener adds a Listener, see AddListener().
+func WithListener(listener Listener) Option {
+	return func(registry *AcornRegistryImpl) {
+		registry.listeners = append(registry.listeners, listener)
+	}
+}
+
+// AddListener adds a Listener that is notified from now on. Listeners are notified in the order they were added.
+func (a *AcornRegistryImpl) AddListener(listener Listener) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.listeners = append(a.listeners, listener)
+}
+
+// notifyBefore tells all listeners that phase begins for acornName, and returns when that was.
+func (a *AcornRegistryImpl) notifyBefore(phase Phase, acornName string) time.Time {
+	for _, listener := range a.currentListeners() {
+		listener.OnBefore(phase, acornName)
+	}
+	return time.Now()
+}
+
+// notifyAfter tells all listeners that phase, which began at began, has ended for acornName.
+func (a *AcornRegistryImpl) notifyAfter(phase Phase, acornName string, began time.Time, err error) {
+	duration := time.Since(began)
+	for _, listener := range a.currentListeners() {
+		listener.OnAfter(phase, acornName, duration, err)
+	}
+}
+
+func (a *AcornRegistryImpl) currentListeners() []Listener {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.listeners
+}
@@ -0,0 +1,97 @@
+package auacorn
+
+import (
+	"github.com/StephanHCB/go-autumn-acorn-registry/test/faila"
+	"github.com/StephanHCB/go-autumn-acorn-registry/test/mockc"
+	"github.com/StephanHCB/go-autumn-acorn-registry/test/para"
+	"github.com/StephanHCB/go-autumn-acorn-registry/test/rec"
+	"testing"
+	"time"
+)
+
+type recordingListener struct{}
+
+func (l recordingListener) OnBefore(phase Phase, acornName string) {
+	rec.Add("before " + string(phase) + " " + acornName)
+}
+
+func (l recordingListener) OnAfter(phase Phase, acornName string, duration time.Duration, err error) {
+	if duration < 0 {
+		rec.Add("negative duration")
+	}
+	if err != nil {
+		rec.Add("failed " + string(phase) + " " + acornName)
+		return
+	}
+	rec.Add("after " + string(phase) + " " + acornName)
+}
+
+func TestListeners_AroundAcornsAndPhases(t *testing.T) {
+	Registry = New(WithListener(recordingListener{}))
+
+	Registry.Register(para.New(&para.ParaImpl{Name: "p1", SetupAfterNames: []string{"p2"}}))
+	Registry.Register(para.New(&para.ParaImpl{Name: "p2"}))
+	rec.Reset()
+	if Registry.Create() != nil {
+		t.FailNow()
+	}
+	assertRecording(t, []string{"before creation ", "after creation "})
+
+	rec.Reset()
+	if Registry.Assemble() != nil {
+		t.FailNow()
+	}
+	assertRecording(t, []string{
+		"before assembly ",
+		"before assembly p1", "after assembly p1",
+		"before assembly p2", "after assembly p2",
+		"after assembly ",
+	})
+
+	rec.Reset()
+	if Registry.Setup() != nil {
+		t.FailNow()
+	}
+	// the setup of p2 is nested inside the setup of p1, which asked for it
+	assertRecording(t, []string{
+		"before setup ",
+		"before setup p1", "p1.PreSetupAcorn",
+		"before setup p2", "p2.PreSetupAcorn", "p2.SetupAcorn", "after setup p2",
+		"p1.SetupAcorn", "after setup p1",
+		"after setup ",
+	})
+
+	rec.Reset()
+	if Registry.Teardown() != nil {
+		t.FailNow()
+	}
+	assertRecording(t, []string{
+		"before teardown ",
+		"before teardown p1", "p1.PreTeardownAcorn", "p1.TeardownAcorn", "after teardown p1",
+		"before teardown p2", "p2.PreTeardownAcorn", "p2.TeardownAcorn", "after teardown p2",
+		"after teardown ",
+	})
+}
+
+func TestListeners_Failure(t *testing.T) {
+	impl := New()
+	Registry = impl
+	impl.AddListener(recordingListener{})
+
+	Registry.Register(faila.New)
+	Registry.Register(mockc.New)
+	Registry.Create()
+	_ = Registry.Assemble()
+
+	rec.Reset()
+	if Registry.Setup() == nil {
+		t.FailNow()
+	}
+	assertRecording(t, []string{
+		"before setup ",
+		"before setup faila",
+		"before setup mockc", "c.SetupAcorn", "after setup mockc",
+		"fa.SetupErr", "failed setup faila",
+		"failed setup ",
+	})
+}
@@ -0,0 +1,70 @@
+package auacorn
+
+import (
+	auacornapi "github.com/StephanHCB/go-autumn-acorn-registry/api"
+	"github.com/StephanHCB/go-autumn-acorn-registry/test/mockb"
+	"github.com/StephanHCB/go-autumn-acorn-registry/test/mockc"
+	"testing"
+)
+
+func TestGet_Success(t *testing.T) {
+	Registry = New()
+
+	Registry.Register(mockc.New)
+	Registry.Create()
+
+	c, err := auacornapi.Get[mockc.MockC](Registry, nil, mockc.MockCName)
+	if err != nil || c == nil || !c.IsC() {
+		t.FailNow()
+	}
+}
+
+func TestGet_UnknownName(t *testing.T) {
+	Registry = New()
+
+	Registry.Register(mockb.New)
+	Registry.Register(mockc.New)
+	Registry.Create()
+
+	requester := Registry.GetAcornByName(mockb.MockBName)
+	_, err := auacornapi.Get[mockc.MockC](Registry, requester, "mockx")
+	if err == nil {
+		t.FailNow()
+	}
+	expected := "acorn 'mockb' requested acorn 'mockx' as mockc.MockC, but no acorn of that name is registered"
+	if err.Error() != expected {
+		t.Errorf("unexpected error message: %s", err.Error())
+	}
+}
+
+func TestGet_WrongInterface(t *testing.T) {
+	Registry = New()
+
+	Registry.Register(mockb.New)
+	Registry.Register(mockc.New)
+	Registry.Create()
+
+	requester := Registry.GetAcornByName(mockb.MockBName)
+	_, err := auacornapi.Get[mockb.MockB](Registry, requester, mockc.MockCName)
+	if err == nil {
+		t.FailNow()
+	}
+	expected := "acorn 'mockb' requested acorn 'mockc' as mockb.MockB, but its type *mockc.MockCImpl does not implement it"
+	if err.Error() != expected {
+		t.Errorf("unexpected error message: %s", err.Error())
+	}
+}
+
+func TestMustGet_Panics(t *testing.T) {
+	Registry = New()
+
+	Registry.Register(mockc.New)
+	Registry.Create()
+
+	defer func() {
+		if r := recover(); r == nil {
+			t.Error("expected MustGet to panic")
+		}
+	}()
+	_ = auacornapi.MustGet[mockb.MockB](Registry, nil, mockc.MockCName)
+}
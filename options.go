@@ -0,0 +1,132 @@
+package auacorn
+
+import (
+	"log"
+	"time"
+)
+
+// Option configures an AcornRegistryImpl. Pass any number of them to New().
+type Option func(registry *AcornRegistryImpl)
+
+// IterationOrder determines the order in which the registry visits Acorns during assembly, setup and teardown.
+//
+// Note that dependencies declared with SetupAfter, TeardownAfter or AddSetupOrderRule still take precedence.
+type IterationOrder uint8
+
+const (
+	// RegistrationOrder visits Acorns in the order of the Register() calls. This is the default.
+	//
+	// An Acorn that replaces another one of the same name keeps the original's position.
+	RegistrationOrder IterationOrder = iota
+
+	// AlphabeticalOrder visits Acorns sorted by their AcornName().
+	AlphabeticalOrder
+)
+
+// WithIterationOrder selects the order in which the registry visits Acorns.
+func WithIterationOrder(order IterationOrder) Option {
+	return func(registry *AcornRegistryImpl) {
+		registry.order = order
+	}
+}
+
+// WithRollbackOnSetupFailure makes a failed Setup() automatically tear down the Acorns that had completed their
+// setup successfully, in reverse order of completion.
+//
+// Acorns whose setup failed or never started are not torn down. Setup() then returns the original error
+// joined with any errors that occurred during the rollback.
+func WithRollbackOnSetupFailure() Option {
+	return func(registry *AcornRegistryImpl) {
+		registry.rollbackOnSetupFailure = true
+	}
+}
+
+// WithParallelSetup makes Setup() set up independent Acorns concurrently, using at most workers goroutines.
+// A value of zero or less means no limit.
+//
+// An Acorn is started as soon as all its prerequisites known after Assemble() are set up, that is,
+// its setup order rules and declared dependencies. Dependencies that are only known from SetupAfter() calls
+// still work, the calling goroutine then sets up the other Acorn, or waits for the goroutine already doing so.
+//
+// Without this option, Setup() sets up one Acorn at a time, in iteration order.
+func WithParallelSetup(workers int) Option {
+	return func(registry *AcornRegistryImpl) {
+		registry.parallelSetup = true
+		registry.setupWorkers = workers
+	}
+}
+
+// WithParallelTeardown makes Teardown() tear down independent Acorns concurrently, using at most workers goroutines.
+// A value of zero or less means no limit.
+//
+// An Acorn is started as soon as its teardown order rules and declared teardown dependencies are satisfied,
+// and all Acorns that were set up after it because of SetupAfter(), setup order rules or declared setup
+// dependencies have been torn down. Acorns that just happened to be set up later are not waited for.
+// TeardownAfter() still works as before, the calling goroutine then tears down the other Acorn, or waits for
+// the goroutine already doing so.
+//
+// Without this option, Teardown() tears down one Acorn at a time, in reverse order of setup completion.
+func WithParallelTeardown(workers int) Option {
+	return func(registry *AcornRegistryImpl) {
+		registry.parallelTeardown = true
+		registry.teardownWorkers = workers
+	}
+}
+
+// WithAcornTimeout limits how long each single AssembleAcorn, SetupAcorn or TeardownAcorn may take, including
+// the time spent waiting in SetupAfter or TeardownAfter.
+//
+// Acorns that implement ContextAcorn get a context with this deadline. For all others, the registry can only
+// notice afterwards that they took too long. Either way, the phase fails with a TimeoutError naming the Acorn.
+func WithAcornTimeout(timeout time.Duration) Option {
+	return func(registry *AcornRegistryImpl) {
+		registry.acornTimeout = timeout
+	}
+}
+
+// WithPhaseTimeout limits how long each of Assemble, Setup and Teardown may take as a whole.
+//
+// When it runs out, the Acorn that is currently running is reported with a TimeoutError, and no more Acorns
+// are started.
+func WithPhaseTimeout(timeout time.Duration) Option {
+	return func(registry *AcornRegistryImpl) {
+		registry.phaseTimeout = timeout
+	}
+}
+
+// DuplicatePolicy determines what Create() does if two constructors registered with Register()
+// produce Acorns with the same AcornName().
+//
+// Constructors registered with RegisterOverride() are never considered duplicates.
+type DuplicatePolicy uint8
+
+const (
+	// DuplicateAllow silently lets the last constructor win. This is the default.
+	DuplicateAllow DuplicatePolicy = iota
+
+	// DuplicateWarn lets the last constructor win, but reports each duplicate to the warning handler.
+	DuplicateWarn
+
+	// DuplicateError makes Create() fail with an error listing all duplicates.
+	DuplicateError
+)
+
+// WithDuplicatePolicy selects what happens if two registered constructors produce Acorns with the same name.
+func WithDuplicatePolicy(policy DuplicatePolicy) Option {
+	return func(registry *AcornRegistryImpl) {
+		registry.duplicatePolicy = policy
+	}
+}
+
+// WithWarningHandler sets the callback that receives warnings, such as duplicates under DuplicateWarn.
+//
+// The default handler writes the warning to the standard logger.
+func WithWarningHandler(handler func(warning error)) Option {
+	return func(registry *AcornRegistryImpl) {
+		registry.warningHandler = handler
+	}
+}
+
+func defaultWarningHandler(warning error) {
+	log.Println("acorn registry warning: " + warning.Error())
+}
@@ -0,0 +1,140 @@
+package auacorn
+
+import (
+	"errors"
+	auacornapi "github.com/StephanHCB/go-autumn-acorn-registry/api"
+)
+
+// phaseRun keeps track of a setup or teardown that may be running in several goroutines at once.
+//
+// All methods must be called while holding the registry lock.
+type phaseRun struct {
+	phase    Phase
+	done     map[auacornapi.Acorn]chan struct{} // closed once the instance has finished
+	errors   map[auacornapi.Acorn]error         // of *LifecycleError, for instances that failed
+	waits    map[auacornapi.Acorn][]edge        // requester -> acorns it is currently waiting for
+	progress chan struct{}                      // signalled whenever an instance finishes
+}
+
+func newPhaseRun(phase Phase) *phaseRun {
+	return &phaseRun{
+		phase:    phase,
+		done:     make(map[auacornapi.Acorn]chan struct{}),
+		errors:   make(map[auacornapi.Acorn]error),
+		waits:    make(map[auacornapi.Acorn][]edge),
+		progress: make(chan struct{}, 1),
+	}
+}
+
+// claim makes everyone else wait for the current goroutine to finish the instance.
+func (r *phaseRun) claim(instance auacornapi.Acorn) {
+	r.done[instance] = make(chan struct{})
+}
+
+// finish records the outcome for a claimed instance, and lets everyone waiting for it continue.
+func (r *phaseRun) finish(instance auacornapi.Acorn, err error) {
+	if err != nil {
+		r.errors[instance] = err
+	}
+	close(r.done[instance])
+	select {
+	case r.progress <- struct{}{}:
+	default:
+	}
+}
+
+// addWait records that requester waits for otherAcorn. A nil requester is not recorded.
+func (r *phaseRun) addWait(requester auacornapi.Acorn, otherAcorn auacornapi.Acorn, via EdgeKind) {
+	if requester != nil {
+		r.waits[requester] = append(r.waits[requester], edge{instance: otherAcorn, via: via})
+	}
+}
+
+// removeWait removes one matching wait recorded by addWait.
+func (r *phaseRun) removeWait(requester auacornapi.Acorn, otherAcorn auacornapi.Acorn) {
+	waits := r.waits[requester]
+	for i, wait := range waits {
+		if wait.instance == otherAcorn {
+			r.waits[requester] = append(waits[:i], waits[i+1:]...)
+			return
+		}
+	}
+}
+
+// findWaitCycle checks whether otherAcorn (directly or indirectly) waits for requester,
+// so requester waiting for otherAcorn would deadlock.
+func (r *phaseRun) findWaitCycle(requester auacornapi.Acorn, otherAcorn auacornapi.Acorn, via EdgeKind) *CycleError {
+	if requester == nil {
+		return nil
+	}
+	visited := make(map[auacornapi.Acorn]bool)
+	path := []edge{{instance: otherAcorn}}
+
+	var search func(current auacornapi.Acorn) bool
+	search = func(current auacornapi.Acorn) bool {
+		if current == requester {
+			return true
+		}
+		visited[current] = true
+		for _, wait := range r.waits[current] {
+			if visited[wait.instance] {
+				continue
+			}
+			path = append(path, wait)
+			if search(wait.instance) {
+				return true
+			}
+			path = path[:len(path)-1]
+		}
+		return false
+	}
+
+	if search(otherAcorn) {
+		return cycleError(r.phase, path, otherAcorn, via)
+	}
+	return nil
+}
+
+// runConcurrently runs each instance handed out by claimReady in its own goroutine, with at most workers
+// goroutines at a time (zero or less means no limit), until claimReady has nothing left and all are finished.
+//
+// claimReady is called again whenever an instance finishes, including those run through SetupAfter() or
+// TeardownAfter(). If stopOnFailure is set, no more instances are started after the first failure.
+//
+// Returns all failures joined.
+func (a *AcornRegistryImpl) runConcurrently(run *phaseRun, workers int, stopOnFailure bool,
+	claimReady func(limit int) []auacornapi.Acorn, execute func(instance auacornapi.Acorn) error) error {
+	completions := make(chan error)
+	running := 0
+	failures := make([]error, 0)
+	for {
+		if len(failures) == 0 || !stopOnFailure {
+			limit := -1
+			if workers > 0 {
+				limit = workers - running
+			}
+			a.mu.Lock()
+			ready := claimReady(limit)
+			a.mu.Unlock()
+			for _, instance := range ready {
+				running++
+				go func(instance auacornapi.Acorn) {
+					completions <- execute(instance)
+				}(instance)
+			}
+		}
+		if running == 0 {
+			return errors.Join(failures...)
+		}
+
+		select {
+		case err := <-completions:
+			running--
+			if err != nil {
+				failures = append(failures, err)
+			}
+		case <-run.progress:
+			// an instance finished through SetupAfter() or TeardownAfter(), which may have made others ready
+		}
+	}
+}
@@ -0,0 +1,91 @@
+package auacorn
+
+import (
+	"fmt"
+	auacornapi "github.com/StephanHCB/go-autumn-acorn-registry/api"
+	"reflect"
+	"runtime"
+	"sort"
+	"strings"
+)
+
+// SkippedRegistration is a registration that Create() did not call the constructor for, because its
+// condition was not met.
+type SkippedRegistration struct {
+	Position    int    // of the registration, in order of all Register...() calls, starting at 0
+	Constructor string // function name of the constructor, e.g. "github.com/me/myapp/pdf.New"
+	Reason      string // why it was skipped
+}
+
+// RegisterIf registers a constructor that Create() only calls if predicate returns true at that time.
+func (a *AcornRegistryImpl) RegisterIf(predicate func() bool, constructor auacornapi.Constructor) {
+	_ = a.violation("RegisterIf")
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.registrations = append(a.registrations, registration{constructor: constructor, predicate: predicate})
+}
+
+// RegisterFor registers a constructor that Create() only calls if profile is active at that time.
+func (a *AcornRegistryImpl) RegisterFor(profile string, constructor auacornapi.Constructor) {
+	_ = a.violation("RegisterFor")
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.registrations = append(a.registrations, registration{constructor: constructor, profile: profile})
+}
+
+// ActivateProfiles activates profiles for RegisterFor(), in addition to those already active.
+func (a *AcornRegistryImpl) ActivateProfiles(profiles ...string) {
+	_ = a.violation("ActivateProfiles")
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	for _, profile := range profiles {
+		a.activeProfiles[profile] = true
+	}
+}
+
+// SkippedRegistrations lists the registrations that the last Create() skipped, in registration order, and why.
+func (a *AcornRegistryImpl) SkippedRegistrations() []SkippedRegistration {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	result := make([]SkippedRegistration, len(a.skippedRegistrations))
+	copy(result, a.skippedRegistrations)
+	return result
+}
+
+// skipReason evaluates the condition of a registration, and returns why it is skipped, or an empty string.
+//
+// Must be called without holding the lock, as the predicate is provided by the application.
+func skipReason(reg registration, activeProfiles []string) string {
+	if reg.profile != "" {
+		for _, active := range activeProfiles {
+			if active == reg.profile {
+				return ""
+			}
+		}
+		if len(activeProfiles) == 0 {
+			return fmt.Sprintf("profile '%s' is not active, no profiles are active", reg.profile)
+		}
+		return fmt.Sprintf("profile '%s' is not active, active profiles are %s", reg.profile, strings.Join(activeProfiles, ", "))
+	}
+	if reg.predicate != nil && !reg.predicate() {
+		return "predicate returned false"
+	}
+	return ""
+}
+
+// sortedActiveProfiles returns the active profiles in alphabetical order. Caller must hold the lock.
+func (a *AcornRegistryImpl) sortedActiveProfiles() []string {
+	result := make([]string, 0, len(a.activeProfiles))
+	for profile := range a.activeProfiles {
+		result = append(result, profile)
+	}
+	sort.Strings(result)
+	return result
+}
+
+func constructorName(constructor auacornapi.Constructor) string {
+	if function := runtime.FuncForPC(reflect.ValueOf(constructor).Pointer()); function != nil {
+		return function.Name()
+	}
+	return fmt.Sprintf("%T", constructor)
+}
@@ -0,0 +1,74 @@
+package auacorn
+
+import (
+	"github.com/StephanHCB/go-autumn-acorn-registry/test/mockc"
+	"github.com/StephanHCB/go-autumn-acorn-registry/test/mockcalt"
+	"github.com/StephanHCB/go-autumn-acorn-registry/test/rec"
+	"testing"
+)
+
+func TestProfiles_ActiveProfile(t *testing.T) {
+	impl := New()
+	Registry = impl
+
+	impl.RegisterFor("local", mockc.New)
+	impl.RegisterFor("prod", mockcalt.New)
+	impl.ActivateProfiles("prod")
+
+	rec.Reset()
+	if Registry.Create() != nil {
+		t.FailNow()
+	}
+	assertRecording(t, []string{"calt.New"})
+
+	skipped := impl.SkippedRegistrations()
+	if len(skipped) != 1 || skipped[0].Position != 0 {
+		t.FailNow()
+	}
+	if skipped[0].Constructor != "github.com/StephanHCB/go-autumn-acorn-registry/test/mockc.New" {
+		t.Errorf("unexpected constructor: %s", skipped[0].Constructor)
+	}
+	if skipped[0].Reason != "profile 'local' is not active, active profiles are prod" {
+		t.Errorf("unexpected reason: %s", skipped[0].Reason)
+	}
+}
+
+func TestProfiles_NoActiveProfile(t *testing.T) {
+	impl := New()
+	Registry = impl
+
+	impl.RegisterFor("prod", mockcalt.New)
+	Registry.Register(mockc.New)
+
+	rec.Reset()
+	if Registry.Create() != nil {
+		t.FailNow()
+	}
+	assertRecording(t, []string{"c.New"})
+	if skipped := impl.SkippedRegistrations(); len(skipped) != 1 || skipped[0].Reason != "profile 'prod' is not active, no profiles are active" {
+		t.FailNow()
+	}
+}
+
+func TestProfiles_Predicate(t *testing.T) {
+	impl := New()
+	Registry = impl
+
+	useAlternative := false
+	impl.RegisterIf(func() bool { return !useAlternative }, mockc.New)
+	impl.RegisterIf(func() bool { return useAlternative }, mockcalt.New)
+	// evaluated during Create, not during registration
+	useAlternative = true
+
+	rec.Reset()
+	if Registry.Create() != nil {
+		t.FailNow()
+	}
+	assertRecording(t, []string{"calt.New"})
+	if skipped := impl.SkippedRegistrations(); len(skipped) != 1 || skipped[0].Position != 0 || skipped[0].Reason != "predicate returned false" {
+		t.FailNow()
+	}
+	if Registry.GetAcornByName(mockc.MockCName) == nil {
+		t.FailNow()
+	}
+}
@@ -1,9 +1,14 @@
 package auacorn
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	auacornapi "github.com/StephanHCB/go-autumn-acorn-registry/api"
+	"runtime"
+	"sort"
+	"sync"
+	"time"
 )
 
 const (
@@ -12,16 +17,74 @@ const (
 	phaseSetupDone    = 3
 	phaseTeardownDone = 4
 
+	phaseStartDone = 5 // only used for the registry, between setup and teardown
+	phaseStopDone  = 6 // only used for the registry, between setup and teardown
+
+	phaseSetupFailed = 90 // only used with rollback on setup failure, so the rollback skips the instance
+
 	phaseInRecursiveSetup    = 93 // special phase value so we can detect circular setup dependencies
 	phaseInRecursiveTeardown = 94 // special phase value so we can detect circular teardown dependencies
 )
 
 type AcornRegistryImpl struct {
-	constructors    []auacornapi.Constructor
-	instancesByName map[string]auacornapi.Acorn
-	phase           uint8
-	phaseByInstance map[auacornapi.Acorn]uint8
-	setupBefore     map[auacornapi.Acorn][]auacornapi.Acorn // dependency -> prerequisites
+	// configuration, see Option
+	duplicatePolicy        DuplicatePolicy
+	warningHandler         func(warning error)
+	order                  IterationOrder
+	rollbackOnSetupFailure bool
+	acornTimeout           time.Duration // for each acorn's AssembleAcorn, SetupAcorn or TeardownAcorn, zero means none
+	phaseTimeout           time.Duration // for each of Assemble, Setup and Teardown as a whole, zero means none
+	parallelSetup          bool
+	setupWorkers           int // limit for parallelSetup, zero means unlimited
+	parallelTeardown       bool
+	teardownWorkers        int // limit for parallelTeardown, zero means unlimited
+	strict                 bool
+	guards                 bool
+	guardMode              GuardMode
+
+	mu sync.Mutex // guards everything below, never held while calling a constructor or lifecycle method
+
+	registrations        []registration
+	instancesByName      map[string]auacornapi.Acorn
+	names                []string // in order of first registration
+	phase                uint8
+	phaseByInstance      map[auacornapi.Acorn]uint8
+	setupBefore          map[auacornapi.Acorn][]edge // dependency -> prerequisites
+	teardownBefore       map[auacornapi.Acorn][]edge // then -> first
+	missingLookups       []error                     // of *MissingAcornError or *LazyAcornNameError
+	assembled            bool                        // Assemble() was called, even if it failed
+	assembling           auacornapi.Acorn            // the acorn whose AssembleAcorn() is running, if any
+	unresolved           []auacornapi.Acorn          // acorns whose AssembleAcorn() looked up unknown acorns
+	setupRun             *phaseRun
+	setupDependents      map[auacornapi.Acorn][]auacornapi.Acorn // prerequisite -> acorns set up after it
+	setupCompleted       []auacornapi.Acorn                      // in order of successful SetupAcorn() completion
+	started              []auacornapi.Acorn                      // in order of successful StartAcorn() completion
+	teardownRun          *phaseRun
+	teardownFailures     []error // of *LifecycleError, collected during Teardown()
+	listeners            []Listener
+	timings              map[string]*AcornTiming
+	violations           []error // of *ProtocolViolationError or *UnreadyAcornError
+	guardAdapters        map[string]auacornapi.GuardAdapter
+	guardTargets         map[auacornapi.Acorn]auacornapi.Acorn // guard proxy -> guarded instance
+	lazyAcorns           map[string]*lazyAcorn
+	lazyInstances        map[auacornapi.Acorn]bool // lazy acorns that were created
+	activeProfiles       map[string]bool
+	skippedRegistrations []SkippedRegistration // by the last Create()
+	observedEdges        []GraphEdge           // in order of first observation
+	observedEdgeSet      map[GraphEdge]bool
+}
+
+// edge points to an acorn that another acorn waits for, and tells how that dependency was specified.
+type edge struct {
+	instance auacornapi.Acorn
+	via      EdgeKind
+}
+
+type registration struct {
+	constructor auacornapi.Constructor
+	override    bool
+	predicate   func() bool // if set, only created if it returns true
+	profile     string      // if set, only created if active
 }
 
 // Registry is the singleton instance of AcornRegistry provided by this library.
@@ -33,27 +96,130 @@ func init() {
 	Registry = New()
 }
 
-func New() auacornapi.AcornRegistry {
-	return &AcornRegistryImpl{
-		constructors:    make([]auacornapi.Constructor, 0),
+// New creates a new registry, optionally configured with any number of Options.
+//
+// It returns the implementation, so you can use the methods that go beyond auacornapi.AcornRegistry,
+// such as those of auacornapi.ExtendedAcornRegistry, or Validate().
+func New(options ...Option) *AcornRegistryImpl {
+	registry := &AcornRegistryImpl{
+		registrations:   make([]registration, 0),
+		warningHandler:  defaultWarningHandler,
 		instancesByName: make(map[string]auacornapi.Acorn),
+		names:           make([]string, 0),
 		phaseByInstance: make(map[auacornapi.Acorn]uint8),
-		setupBefore:     make(map[auacornapi.Acorn][]auacornapi.Acorn),
+		setupBefore:     make(map[auacornapi.Acorn][]edge),
+		teardownBefore:  make(map[auacornapi.Acorn][]edge),
+		setupCompleted:  make([]auacornapi.Acorn, 0),
+		timings:         make(map[string]*AcornTiming),
+		observedEdgeSet: make(map[GraphEdge]bool),
+		guardAdapters:   make(map[string]auacornapi.GuardAdapter),
+		guardTargets:    make(map[auacornapi.Acorn]auacornapi.Acorn),
+		lazyAcorns:      make(map[string]*lazyAcorn),
+		lazyInstances:   make(map[auacornapi.Acorn]bool),
+		activeProfiles:  make(map[string]bool),
 	}
+	for _, option := range options {
+		option(registry)
+	}
+	return registry
 }
 
 func (a *AcornRegistryImpl) Register(constructor auacornapi.Constructor) {
-	a.constructors = append(a.constructors, constructor)
+	_ = a.violation("Register")
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.registrations = append(a.registrations, registration{constructor: constructor})
+}
+
+// RegisterOverride registers a constructor that deliberately replaces an earlier registration with the same name.
+func (a *AcornRegistryImpl) RegisterOverride(constructor auacornapi.Constructor) {
+	_ = a.violation("RegisterOverride")
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.registrations = append(a.registrations, registration{constructor: constructor, override: true})
 }
 
-func (a *AcornRegistryImpl) Create() {
-	for _, constructor := range a.constructors {
-		instance := constructor()
+func (a *AcornRegistryImpl) Create() (err error) {
+	if err := a.violation("Create"); err != nil {
+		return err
+	}
+
+	began := a.notifyBefore(PhaseCreation, "")
+	defer func() { a.notifyAfter(PhaseCreation, "", began, err) }()
+
+	a.mu.Lock()
+	registrations := make([]registration, len(a.registrations))
+	copy(registrations, a.registrations)
+	activeProfiles := a.sortedActiveProfiles()
+	a.mu.Unlock()
+
+	// constructors and predicates are called without holding the lock, so a misbehaving one cannot deadlock the registry
+	instances := make([]auacornapi.Acorn, len(registrations))
+	created := make([]bool, len(registrations))
+	skipped := make([]SkippedRegistration, 0)
+	for i, reg := range registrations {
+		if reason := skipReason(reg, activeProfiles); reason != "" {
+			skipped = append(skipped, SkippedRegistration{Position: i, Constructor: constructorName(reg.constructor), Reason: reason})
+			continue
+		}
+		instances[i] = reg.constructor()
+		created[i] = true
+	}
+
+	a.mu.Lock()
+	a.skippedRegistrations = skipped
+	duplicates := make([]error, 0)
+	warnings := make([]error, 0)
+	for i, reg := range registrations {
+		if !created[i] {
+			continue
+		}
+		instance := instances[i]
 		name := instance.AcornName()
-		a.instancesByName[name] = instance
-		a.phaseByInstance[instance] = phaseCreateDone
+		if replaced, ok := a.instancesByName[name]; ok && !reg.override {
+			duplicate := &DuplicateAcornError{
+				AcornName:     name,
+				ReplacedType:  fmt.Sprintf("%T", replaced),
+				ReplacingType: fmt.Sprintf("%T", instance),
+			}
+			switch a.duplicatePolicy {
+			case DuplicateWarn:
+				warnings = append(warnings, duplicate)
+			case DuplicateError:
+				duplicates = append(duplicates, duplicate)
+			}
+		}
+		a.putInstance(name, instance)
+	}
+	if len(duplicates) == 0 {
+		a.phase = phaseCreateDone
+	}
+	a.mu.Unlock()
+
+	for _, warning := range warnings {
+		a.warningHandler(warning)
+	}
+	return errors.Join(duplicates...)
+}
+
+// putInstance adds or replaces the instance for name. A replacement keeps the position of the original.
+// Caller must hold the lock.
+func (a *AcornRegistryImpl) putInstance(name string, instance auacornapi.Acorn) {
+	if _, ok := a.instancesByName[name]; !ok {
+		a.names = append(a.names, name)
+	}
+	a.instancesByName[name] = instance
+	a.phaseByInstance[instance] = phaseCreateDone
+}
+
+// orderedNames returns all acorn names in the configured IterationOrder. Caller must hold the lock.
+func (a *AcornRegistryImpl) orderedNames() []string {
+	result := make([]string, len(a.names))
+	copy(result, a.names)
+	if a.order == AlphabeticalOrder {
+		sort.Strings(result)
 	}
-	a.phase = phaseCreateDone
+	return result
 }
 
 // CreateOverride lets you override an instance after create.
@@ -62,136 +228,244 @@ func (a *AcornRegistryImpl) Create() {
 //
 // useful for testing
 func (a *AcornRegistryImpl) CreateOverride(name string, instance auacornapi.Acorn) {
-	a.instancesByName[name] = instance
-	a.phaseByInstance[instance] = phaseCreateDone
+	_ = a.violation("CreateOverride")
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.putInstance(name, instance)
 }
 
-func (a *AcornRegistryImpl) lifecycleStep(step string, fromPhase uint8, toPhase uint8, receiver func(auacornapi.Acorn) error) error {
-	for name, instance := range a.instancesByName {
-		if a.phaseByInstance[instance] == fromPhase {
-			// only do the phase if it hasn't already been done
+// lifecycleStep calls receiver for each instance in fromPhase, without holding the lock, so it can call back
+// into the registry.
+func (a *AcornRegistryImpl) lifecycleStep(step Phase, fromPhase uint8, toPhase uint8, receiver func(auacornapi.Acorn) error) error {
+	// lazy acorns created along the way are in fromPhase, too, so repeat until none are left
+	for pending := a.instancesInPhase(fromPhase); len(pending) > 0; pending = a.instancesInPhase(fromPhase) {
+		for _, instance := range pending {
+			a.mu.Lock()
+			due := a.phaseByInstance[instance] == fromPhase
+			a.mu.Unlock()
+			if !due {
+				// only do the phase if it hasn't already been done
+				continue
+			}
+
 			err := receiver(instance)
 			if err != nil {
-				return fmt.Errorf("error during %s of Acorn '%s': %s", step, name, err.Error())
+				return wrapLifecycleError(step, instance, err)
 			}
+			a.mu.Lock()
 			a.phaseByInstance[instance] = toPhase
+			a.mu.Unlock()
 		}
 	}
+	a.mu.Lock()
 	a.phase = toPhase
+	a.mu.Unlock()
 	return nil
 }
 
+// instancesInPhase returns all instances currently in the given phase, in iteration order.
+func (a *AcornRegistryImpl) instancesInPhase(phase uint8) []auacornapi.Acorn {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	result := make([]auacornapi.Acorn, 0, len(a.names))
+	for _, name := range a.orderedNames() {
+		instance := a.instancesByName[name]
+		if a.phaseByInstance[instance] == phase {
+			result = append(result, instance)
+		}
+	}
+	return result
+}
+
 // SkipAssemble lets you mark an instance as already assembled, so it will be skipped during Assemble().
 //
 // useful for testing
 func (a *AcornRegistryImpl) SkipAssemble(instance auacornapi.Acorn) {
+	_ = a.violation("SkipAssemble")
+	a.mu.Lock()
+	defer a.mu.Unlock()
 	a.phaseByInstance[instance] = phaseAssembleDone
 }
 
 func (a *AcornRegistryImpl) Assemble() error {
+	return a.AssembleCtx(context.Background())
+}
+
+// AssembleCtx is Assemble, but stops calling acorns once ctx is done.
+func (a *AcornRegistryImpl) AssembleCtx(ctx context.Context) (err error) {
+	a.mu.Lock()
 	if a.phase != phaseCreateDone {
-		return errors.New("wrong acorn registry phase order: Assemble() comes after Create()")
+		a.mu.Unlock()
+		return &PhaseOrderError{Method: "Assemble", Detail: "must come after Create()"}
 	}
-	return a.lifecycleStep("assembly", phaseCreateDone, phaseAssembleDone, func(instance auacornapi.Acorn) error {
-		return instance.AssembleAcorn(a)
-	})
-}
+	a.missingLookups = make([]error, 0)
+	a.unresolved = make([]auacornapi.Acorn, 0)
+	a.assembled = true
+	a.mu.Unlock()
 
-// SkipSetup lets you mark an instance as already set up, so it will be skipped during Setup().
-//
-// useful for testing
-func (a *AcornRegistryImpl) SkipSetup(instance auacornapi.Acorn) {
-	a.phaseByInstance[instance] = phaseSetupDone
-}
+	began := a.notifyBefore(PhaseAssembly, "")
+	defer func() { a.notifyAfter(PhaseAssembly, "", began, err) }()
 
-func (a *AcornRegistryImpl) injectExtraSetupAfterCallsThenSetup(instance auacornapi.Acorn) error {
-	extraPrerequisites, ok := a.setupBefore[instance]
-	if ok {
-		for _, prerequisite := range extraPrerequisites {
-			err := a.SetupAfter(prerequisite)
-			if err != nil {
-				return err
-			}
+	ctx, cancel := a.withPhaseTimeout(ctx)
+	defer cancel()
+	err = a.lifecycleStep(PhaseAssembly, phaseCreateDone, phaseAssembleDone, func(instance auacornapi.Acorn) error {
+		if err := a.assembleRecordingMissingLookups(ctx, instance); err != nil {
+			return err
 		}
+		a.instantiateDeclaredLazy(instance)
+		return nil
+	})
+	if err != nil {
+		return err
 	}
-	return instance.SetupAcorn(a)
-}
+	declarations := a.readDeclarations()
 
-func (a *AcornRegistryImpl) Setup() error {
-	if a.phase != phaseAssembleDone {
-		return errors.New("wrong acorn registry phase order: Setup() comes after Assemble()")
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if len(a.missingLookups) == 0 {
+		a.readDeclaredDependencies(declarations)
 	}
-	return a.lifecycleStep("setup", phaseAssembleDone, phaseSetupDone, func(instance auacornapi.Acorn) error {
-		return a.injectExtraSetupAfterCallsThenSetup(instance)
-	})
+	if len(a.missingLookups) > 0 {
+		// assembly is incomplete, so do not allow Setup(), and assemble the acorns with missing lookups again next time
+		a.phase = phaseCreateDone
+		for _, instance := range a.unresolved {
+			a.phaseByInstance[instance] = phaseCreateDone
+		}
+		return errors.Join(a.missingLookups...)
+	}
+	if err := a.findCycle(PhaseSetup, a.setupBefore); err != nil {
+		a.phase = phaseCreateDone
+		return err
+	}
+	if err := a.findCycle(PhaseTeardown, a.teardownBefore); err != nil {
+		a.phase = phaseCreateDone
+		return err
+	}
+	return nil
 }
 
-// SkipTeardown lets you mark an instance as already torn down, so it will be skipped during Teardown().
+// assembleRecordingMissingLookups calls AssembleAcorn, making unresolved lookups attributable to the instance.
 //
-// useful for testing
-func (a *AcornRegistryImpl) SkipTeardown(instance auacornapi.Acorn) {
-	a.phaseByInstance[instance] = phaseTeardownDone
+// If the instance looked up unknown acorns, its failure is reported together with all other missing lookups
+// at the end of Assemble(), so we continue with the next instance. This includes panics from type-casting
+// the nil return value of GetAcornByName. Any other panic is passed on.
+func (a *AcornRegistryImpl) assembleRecordingMissingLookups(ctx context.Context, instance auacornapi.Acorn) (err error) {
+	a.mu.Lock()
+	missingBefore := len(a.missingLookups)
+	a.assembling = instance
+	a.mu.Unlock()
+	defer func() {
+		a.mu.Lock()
+		missingAfter := len(a.missingLookups)
+		a.assembling = nil
+		if missingAfter > missingBefore {
+			a.unresolved = append(a.unresolved, instance)
+		}
+		a.mu.Unlock()
+		if missingAfter > missingBefore {
+			if recovered := recover(); recovered != nil {
+				if _, ok := recovered.(*runtime.TypeAssertionError); !ok {
+					panic(recovered)
+				}
+			}
+			err = nil
+		}
+	}()
+	return a.callAcorn(ctx, PhaseAssembly, instance)
 }
 
-func (a *AcornRegistryImpl) Teardown() error {
-	// we allow teardown even for lower phase numbers, so partial setup can be cleaned up
-	return a.lifecycleStep("teardown", phaseSetupDone, phaseTeardownDone, func(instance auacornapi.Acorn) error {
-		return instance.TeardownAcorn(a)
-	})
+// cycleError builds a CycleError from a chain of acorns waiting for each other, which must contain otherAcorn.
+//
+// The path runs from the last occurrence of otherAcorn along the chain, then back to otherAcorn via the new edge.
+func cycleError(phase Phase, stack []edge, otherAcorn auacornapi.Acorn, via EdgeKind) *CycleError {
+	start := len(stack) - 1
+	for start > 0 && stack[start].instance != otherAcorn {
+		start--
+	}
+	path := make([]CycleStep, 0, len(stack)-start+1)
+	path = append(path, CycleStep{AcornName: otherAcorn.AcornName()})
+	for _, entry := range stack[start+1:] {
+		path = append(path, CycleStep{AcornName: entry.instance.AcornName(), Via: entry.via})
+	}
+	path = append(path, CycleStep{AcornName: otherAcorn.AcornName(), Via: via})
+	return &CycleError{Phase: phase, AcornName: otherAcorn.AcornName(), Path: path}
 }
 
 func (a *AcornRegistryImpl) GetAcornByName(acornName string) auacornapi.Acorn {
-	return a.instancesByName[acornName]
+	_ = a.violation("GetAcornByName")
+	return a.getAcornByName(nil, acornName)
 }
 
-func (a *AcornRegistryImpl) SetupAfter(otherAcorn auacornapi.Acorn) error {
-	if a.phase != phaseAssembleDone {
-		return errors.New("wrong acorn registry phase for call to SetupAfter() - only allowed during setup phase")
-	}
-	if a.phaseByInstance[otherAcorn] == phaseInRecursiveSetup {
-		// circular dependency
-		return fmt.Errorf("circular setup dependency involving Acorn %s - not allowed", otherAcorn.AcornName())
-	}
-	if a.phaseByInstance[otherAcorn] != phaseAssembleDone {
-		// was already set up, that is ok
-		return nil
+// getAcornByName looks up an acorn by name. Lookups of unknown names during assembly are recorded, so Assemble()
+// can report them. Without a requester, such as through the global Registry, the lookup is attributed to the acorn
+// that is being assembled.
+func (a *AcornRegistryImpl) getAcornByName(requester auacornapi.Acorn, acornName string) auacornapi.Acorn {
+	a.mu.Lock()
+	lazy := a.wantsLazy(acornName)
+	a.mu.Unlock()
+	if lazy {
+		a.instantiateLazy(acornName)
 	}
 
-	a.phaseByInstance[otherAcorn] = phaseInRecursiveSetup
-	err := a.injectExtraSetupAfterCallsThenSetup(otherAcorn)
-	a.phaseByInstance[otherAcorn] = phaseSetupDone
-	return err
-}
-
-func (a *AcornRegistryImpl) TeardownAfter(otherAcorn auacornapi.Acorn) error {
-	if a.phaseByInstance[otherAcorn] == phaseInRecursiveTeardown {
-		// circular dependency
-		return fmt.Errorf("circular teardown dependency involving Acorn %s - not allowed", otherAcorn.AcornName())
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	instance, ok := a.instancesByName[acornName]
+	if requester == nil {
+		requester = a.assembling
 	}
-	if a.phaseByInstance[otherAcorn] != phaseSetupDone {
-		// was already torn down, or never set up, that is ok
-		return nil
+	if requester != nil && a.phase == phaseCreateDone {
+		a.observeEdge(requester.AcornName(), acornName, EdgeLookup)
 	}
-
-	a.phaseByInstance[otherAcorn] = phaseInRecursiveTeardown
-	err := otherAcorn.TeardownAcorn(a)
-	a.phaseByInstance[otherAcorn] = phaseTeardownDone
-	return err
+	if !ok && requester != nil && a.phase == phaseCreateDone {
+		lookup := MissingAcornError{Requester: requester.AcornName(), AcornName: acornName}
+		for _, known := range a.missingLookups {
+			if missing, ok := known.(*MissingAcornError); ok && *missing == lookup {
+				return nil
+			}
+		}
+		a.missingLookups = append(a.missingLookups, &lookup)
+	}
+	return instance
 }
 
 func (a *AcornRegistryImpl) AddSetupOrderRule(prerequisite auacornapi.Acorn, dependency auacornapi.Acorn) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
 	if a.phase != phaseCreateDone {
-		return errors.New("wrong acorn registry phase for call to AddSetupOrderRule() - only allowed during assembly phase")
+		return &PhaseOrderError{Method: "AddSetupOrderRule", Detail: "only allowed during assembly phase"}
 	}
 	if prerequisite == nil || dependency == nil {
 		return errors.New("cannot add setup order rule for nil acorns")
 	}
+	prerequisite, dependency = a.unguarded(prerequisite), a.unguarded(dependency)
 
 	currentSetupBefore, ok := a.setupBefore[dependency]
 	if !ok {
-		currentSetupBefore = make([]auacornapi.Acorn, 0)
+		currentSetupBefore = make([]edge, 0)
+	}
+
+	a.setupBefore[dependency] = append(currentSetupBefore, edge{instance: prerequisite, via: EdgeSetupOrderRule})
+	a.observeEdge(dependency.AcornName(), prerequisite.AcornName(), EdgeSetupOrderRule)
+	return nil
+}
+
+func (a *AcornRegistryImpl) AddTeardownOrderRule(first auacornapi.Acorn, then auacornapi.Acorn) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.phase != phaseCreateDone {
+		return &PhaseOrderError{Method: "AddTeardownOrderRule", Detail: "only allowed during assembly phase"}
+	}
+	if first == nil || then == nil {
+		return errors.New("cannot add teardown order rule for nil acorns")
+	}
+	first, then = a.unguarded(first), a.unguarded(then)
+
+	currentTeardownBefore, ok := a.teardownBefore[then]
+	if !ok {
+		currentTeardownBefore = make([]edge, 0)
 	}
 
-	a.setupBefore[dependency] = append(currentSetupBefore, prerequisite)
+	a.teardownBefore[then] = append(currentTeardownBefore, edge{instance: first, via: EdgeTeardownOrderRule})
+	a.observeEdge(then.AcornName(), first.AcornName(), EdgeTeardownOrderRule)
 	return nil
 }
@@ -1,13 +1,24 @@
 package auacorn
 
 import (
+	"errors"
 	"fmt"
+	auacornapi "github.com/StephanHCB/go-autumn-acorn-registry/api"
 	"github.com/StephanHCB/go-autumn-acorn-registry/test/circlea"
 	"github.com/StephanHCB/go-autumn-acorn-registry/test/circleb"
 	"github.com/StephanHCB/go-autumn-acorn-registry/test/circleint"
+	"github.com/StephanHCB/go-autumn-acorn-registry/test/declareda"
+	"github.com/StephanHCB/go-autumn-acorn-registry/test/declaredb"
+	"github.com/StephanHCB/go-autumn-acorn-registry/test/declaredc"
+	"github.com/StephanHCB/go-autumn-acorn-registry/test/faila"
+	"github.com/StephanHCB/go-autumn-acorn-registry/test/hook"
+	"github.com/StephanHCB/go-autumn-acorn-registry/test/missinga"
+	"github.com/StephanHCB/go-autumn-acorn-registry/test/missingb"
+	"github.com/StephanHCB/go-autumn-acorn-registry/test/missingc"
 	"github.com/StephanHCB/go-autumn-acorn-registry/test/mocka"
 	"github.com/StephanHCB/go-autumn-acorn-registry/test/mockb"
 	"github.com/StephanHCB/go-autumn-acorn-registry/test/mockc"
+	"github.com/StephanHCB/go-autumn-acorn-registry/test/mockcalt"
 	"github.com/StephanHCB/go-autumn-acorn-registry/test/rec"
 	"github.com/StephanHCB/go-autumn-acorn-registry/test/revcirclea"
 	"github.com/StephanHCB/go-autumn-acorn-registry/test/revcircleb"
@@ -50,15 +61,8 @@ func TestRegistry_NormalLifecycle(t *testing.T) {
 	if err != nil {
 		t.FailNow()
 	}
-	// assemble has no order
-	assertRecording(t,
-		[]string{"a.AssembleAcorn", "b.AssembleAcorn", "c.AssembleAcorn"},
-		[]string{"a.AssembleAcorn", "c.AssembleAcorn", "b.AssembleAcorn"},
-		[]string{"b.AssembleAcorn", "a.AssembleAcorn", "c.AssembleAcorn"},
-		[]string{"b.AssembleAcorn", "c.AssembleAcorn", "a.AssembleAcorn"},
-		[]string{"c.AssembleAcorn", "a.AssembleAcorn", "b.AssembleAcorn"},
-		[]string{"c.AssembleAcorn", "b.AssembleAcorn", "a.AssembleAcorn"},
-	)
+	// assemble in registration order
+	assertRecording(t, []string{"a.AssembleAcorn", "b.AssembleAcorn", "c.AssembleAcorn"})
 
 	rec.Reset()
 	err = Registry.Setup()
@@ -81,10 +85,8 @@ func TestRegistry_NormalLifecycle(t *testing.T) {
 	if err != nil {
 		t.FailNow()
 	}
-	// dependencies make c tear down first, then a and b in any order (no dependencies)
-	assertRecording(t,
-		[]string{"c.TeardownAcorn", "a.TeardownAcorn", "b.TeardownAcorn"},
-		[]string{"c.TeardownAcorn", "b.TeardownAcorn", "a.TeardownAcorn"})
+	// reverse setup order would be a, b, c, but a's dependency makes c tear down before a
+	assertRecording(t, []string{"c.TeardownAcorn", "a.TeardownAcorn", "b.TeardownAcorn"})
 }
 
 func TestRegistry_CircleDetection(t *testing.T) {
@@ -102,21 +104,16 @@ func TestRegistry_CircleDetection(t *testing.T) {
 	if err != nil {
 		t.FailNow()
 	}
-	// assemble has no order
-	assertRecording(t,
-		[]string{"a.AssembleAcorn", "b.AssembleAcorn"},
-		[]string{"b.AssembleAcorn", "a.AssembleAcorn"},
-	)
+	// assemble in registration order
+	assertRecording(t, []string{"a.AssembleAcorn", "b.AssembleAcorn"})
 
 	rec.Reset()
 	err = Registry.Setup()
 	if err == nil {
 		t.FailNow()
 	}
-	assertRecording(t,
-		[]string{"a.PreSetupAcorn", "b.PreSetupAcorn", "a.PreSetupAcorn", "a.SetupErr", "b.SetupErr", "a.SetupErr"},
-		[]string{"b.PreSetupAcorn", "a.PreSetupAcorn", "b.PreSetupAcorn", "b.SetupErr", "a.SetupErr", "b.SetupErr"},
-	)
+	// a is already being set up, so it is not entered a second time
+	assertRecording(t, []string{"a.PreSetupAcorn", "b.PreSetupAcorn", "b.SetupErr", "a.SetupErr"})
 
 	a := Registry.GetAcornByName(circleint.MockAName).(circleint.MockA)
 	b := Registry.GetAcornByName(circleint.MockBName).(circleint.MockB)
@@ -130,10 +127,8 @@ func TestRegistry_CircleDetection(t *testing.T) {
 	if err == nil {
 		t.FailNow()
 	}
-	assertRecording(t,
-		[]string{"a.PreTeardownAcorn", "b.PreTeardownAcorn", "a.PreTeardownAcorn", "a.TeardownErr", "b.TeardownErr", "a.TeardownErr"},
-		[]string{"b.PreTeardownAcorn", "a.PreTeardownAcorn", "b.PreTeardownAcorn", "b.TeardownErr", "a.TeardownErr", "b.TeardownErr"},
-	)
+	// a is already being torn down, so it is not entered a second time, and the failure of b is not passed on to a
+	assertRecording(t, []string{"a.PreTeardownAcorn", "b.PreTeardownAcorn", "b.TeardownErr", "a.TeardownAcorn"})
 }
 
 func TestRegistry_NormalLifecycle_WithReverseDependency(t *testing.T) {
@@ -151,11 +146,8 @@ func TestRegistry_NormalLifecycle_WithReverseDependency(t *testing.T) {
 	if err != nil {
 		t.FailNow()
 	}
-	// assemble has no order
-	assertRecording(t,
-		[]string{"a.AssembleAcorn", "b.AssembleAcorn"},
-		[]string{"b.AssembleAcorn", "a.AssembleAcorn"},
-	)
+	// assemble in registration order
+	assertRecording(t, []string{"a.AssembleAcorn", "b.AssembleAcorn"})
 
 	rec.Reset()
 	err = Registry.Setup()
@@ -177,10 +169,8 @@ func TestRegistry_NormalLifecycle_WithReverseDependency(t *testing.T) {
 	if err != nil {
 		t.FailNow()
 	}
-	// tear down a and b in any order (no dependencies)
-	assertRecording(t,
-		[]string{"a.TeardownAcorn", "b.TeardownAcorn"},
-		[]string{"b.TeardownAcorn", "a.TeardownAcorn"})
+	// tear down in reverse setup order
+	assertRecording(t, []string{"a.TeardownAcorn", "b.TeardownAcorn"})
 }
 
 func TestRegistry_CircleDetection_WithReverseDependency(t *testing.T) {
@@ -198,23 +188,16 @@ func TestRegistry_CircleDetection_WithReverseDependency(t *testing.T) {
 	if err != nil {
 		t.FailNow()
 	}
-	// assemble has no order
-	assertRecording(t,
-		[]string{"a.AssembleAcorn", "b.AssembleAcorn"},
-		[]string{"b.AssembleAcorn", "a.AssembleAcorn"},
-	)
+	// assemble in registration order
+	assertRecording(t, []string{"a.AssembleAcorn", "b.AssembleAcorn"})
 
 	rec.Reset()
 	err = Registry.Setup()
 	if err == nil {
 		t.FailNow()
 	}
-	assertRecording(t,
-		// it picks a first, and the circle is detected the second time the additional order rule triggers
-		[]string{"b.PreSetupAcorn", "b.SetupErr"},
-		// it picks b first, and the circle is detected the second time it enters b.SetupAcorn()
-		[]string{"b.PreSetupAcorn", "b.PreSetupAcorn", "b.SetupErr", "b.SetupErr"},
-	)
+	// it picks a first, and the circle is detected the second time the additional order rule triggers
+	assertRecording(t, []string{"b.PreSetupAcorn", "b.SetupErr"})
 
 	a := Registry.GetAcornByName(revcircleint.RevCircleAName).(revcircleint.RevCircleA)
 	b := Registry.GetAcornByName(revcircleint.RevCircleBName).(revcircleint.RevCircleB)
@@ -228,8 +211,582 @@ func TestRegistry_CircleDetection_WithReverseDependency(t *testing.T) {
 	if err == nil {
 		t.FailNow()
 	}
-	assertRecording(t,
-		[]string{"a.PreTeardownAcorn", "b.PreTeardownAcorn", "a.PreTeardownAcorn", "a.TeardownErr", "b.TeardownErr", "a.TeardownErr"},
-		[]string{"b.PreTeardownAcorn", "a.PreTeardownAcorn", "b.PreTeardownAcorn", "b.TeardownErr", "a.TeardownErr", "b.TeardownErr"},
-	)
+	// a is already being torn down, so it is not entered a second time, and the failure of b is not passed on to a
+	assertRecording(t, []string{"a.PreTeardownAcorn", "b.PreTeardownAcorn", "b.TeardownErr", "a.TeardownAcorn"})
+}
+
+func TestRegistry_IterationOrder(t *testing.T) {
+	for _, order := range []IterationOrder{RegistrationOrder, AlphabeticalOrder} {
+		Registry = New(WithIterationOrder(order))
+
+		Registry.Register(mockc.New)
+		Registry.Register(mockb.New)
+		Registry.Register(mocka.New)
+
+		rec.Reset()
+		Registry.Create()
+		assertRecording(t, []string{"c.New", "b.New", "a.New"})
+
+		rec.Reset()
+		err := Registry.Assemble()
+		if err != nil {
+			t.FailNow()
+		}
+		if order == AlphabeticalOrder {
+			assertRecording(t, []string{"a.AssembleAcorn", "b.AssembleAcorn", "c.AssembleAcorn"})
+		} else {
+			assertRecording(t, []string{"c.AssembleAcorn", "b.AssembleAcorn", "a.AssembleAcorn"})
+		}
+	}
+}
+
+func TestRegistry_IterationOrder_OverrideKeepsPosition(t *testing.T) {
+	Registry = New()
+
+	Registry.Register(mocka.New)
+	Registry.Register(mockb.New)
+	Registry.Register(mockc.New)
+	Registry.Create()
+	Registry.CreateOverride(mocka.MockAName, &mocka.MockAImpl{})
+
+	rec.Reset()
+	err := Registry.Assemble()
+	if err != nil {
+		t.FailNow()
+	}
+	assertRecording(t, []string{"a.AssembleAcorn", "b.AssembleAcorn", "c.AssembleAcorn"})
+}
+
+func TestRegistry_Duplicates_AllowedByDefault(t *testing.T) {
+	Registry = New()
+
+	Registry.Register(mockc.New)
+	Registry.Register(mockcalt.New)
+
+	rec.Reset()
+	err := Registry.Create()
+	if err != nil {
+		t.FailNow()
+	}
+	assertRecording(t, []string{"c.New", "calt.New"})
+
+	// last one wins
+	if _, ok := Registry.GetAcornByName(mockc.MockCName).(*mockcalt.MockCAltImpl); !ok {
+		t.FailNow()
+	}
+}
+
+func TestRegistry_Duplicates_Warn(t *testing.T) {
+	warnings := make([]string, 0)
+	Registry = New(WithDuplicatePolicy(DuplicateWarn), WithWarningHandler(func(warning error) {
+		warnings = append(warnings, warning.Error())
+	}))
+
+	Registry.Register(mockc.New)
+	Registry.Register(mockcalt.New)
+
+	err := Registry.Create()
+	if err != nil {
+		t.FailNow()
+	}
+	expected := "duplicate acorn name 'mockc': *mockcalt.MockCAltImpl replaces *mockc.MockCImpl - use RegisterOverride() if this is intended"
+	if len(warnings) != 1 || warnings[0] != expected {
+		t.Errorf("unexpected warnings: %v", warnings)
+	}
+	if _, ok := Registry.GetAcornByName(mockc.MockCName).(*mockcalt.MockCAltImpl); !ok {
+		t.FailNow()
+	}
+}
+
+func TestRegistry_Duplicates_Error(t *testing.T) {
+	Registry = New(WithDuplicatePolicy(DuplicateError))
+
+	Registry.Register(mockc.New)
+	Registry.Register(mockcalt.New)
+
+	err := Registry.Create()
+	if err == nil {
+		t.FailNow()
+	}
+	var duplicateErr *DuplicateAcornError
+	if !errors.As(err, &duplicateErr) ||
+		duplicateErr.ReplacedType != "*mockc.MockCImpl" || duplicateErr.ReplacingType != "*mockcalt.MockCAltImpl" {
+		t.Errorf("unexpected error: %s", err.Error())
+	}
+
+	err = Registry.Assemble()
+	if err == nil {
+		t.FailNow()
+	}
+}
+
+func TestRegistry_Duplicates_ExplicitOverride(t *testing.T) {
+	impl := New(WithDuplicatePolicy(DuplicateError))
+	Registry = impl
+
+	Registry.Register(mockc.New)
+	impl.RegisterOverride(mockcalt.New)
+
+	err := Registry.Create()
+	if err != nil {
+		t.FailNow()
+	}
+	if _, ok := Registry.GetAcornByName(mockc.MockCName).(*mockcalt.MockCAltImpl); !ok {
+		t.FailNow()
+	}
+}
+
+func TestRegistry_MissingDependencies(t *testing.T) {
+	Registry = New()
+
+	Registry.Register(missinga.New)
+	Registry.Register(missingb.New)
+	Registry.Register(mockc.New)
+
+	rec.Reset()
+	Registry.Create()
+
+	rec.Reset()
+	err := Registry.Assemble()
+	if err == nil {
+		t.FailNow()
+	}
+	// both acorns got to fail, even though b panics on the nil lookup result
+	expected := "acorn 'missinga' requested unknown acorn 'unknownx'\n" +
+		"acorn 'missingb' requested unknown acorn 'unknowny'"
+	if err.Error() != expected {
+		t.Errorf("unexpected error message: %s", err.Error())
+	}
+	var missingErr *MissingAcornError
+	if !errors.As(err, &missingErr) || missingErr.Requester != missinga.MissingAName {
+		t.FailNow()
+	}
+
+	err = Registry.Setup()
+	if err == nil {
+		t.FailNow()
+	}
+}
+
+func TestRegistry_MissingDependencies_AssembleTwice(t *testing.T) {
+	Registry = New()
+
+	Registry.Register(missinga.New)
+	Registry.Register(mockc.New)
+	Registry.Create()
+	if Registry.Assemble() == nil {
+		t.FailNow()
+	}
+
+	// the lookup is still unresolved, so a second attempt must not pretend everything is assembled
+	rec.Reset()
+	err := Registry.Assemble()
+	if err == nil || err.Error() != "acorn 'missinga' requested unknown acorn 'unknownx'" {
+		t.Errorf("unexpected error: %v", err)
+	}
+	assertRecording(t, []string{"a.AssembleAcorn", "a.AssembleErr"})
+
+	if Registry.Setup() == nil {
+		t.FailNow()
+	}
+}
+
+func TestRegistry_MissingDependencies_UnrelatedPanic(t *testing.T) {
+	Registry = New()
+
+	Registry.Register(missingc.New)
+	Registry.Register(mockc.New)
+	Registry.Create()
+
+	defer func() {
+		if r := recover(); r != "unrelated failure" {
+			t.Errorf("unexpected panic: %v", r)
+		}
+	}()
+	_ = Registry.Assemble()
+	t.FailNow()
+}
+
+func TestRegistry_MissingDependencies_GlobalRegistry(t *testing.T) {
+	Registry = New()
+
+	Registry.Register(hook.New(&hook.HookImpl{Name: "hooked", OnAssemble: func(_ auacornapi.AcornRegistry) {
+		// bypasses the registry passed to AssembleAcorn
+		_ = Registry.GetAcornByName("unknownx").(mockc.MockC)
+	}}))
+	Registry.Register(mockc.New)
+	Registry.Create()
+	// lookups by the application itself are not recorded
+	_ = Registry.GetAcornByName("unknowny")
+
+	err := Registry.Assemble()
+	if err == nil {
+		t.FailNow()
+	}
+	if err.Error() != "acorn 'hooked' requested unknown acorn 'unknownx'" {
+		t.Errorf("unexpected error message: %s", err.Error())
+	}
+}
+
+func TestRegistry_ErrorTypes(t *testing.T) {
+	Registry = New()
+
+	Registry.Register(faila.New)
+	Registry.Register(mockc.New)
+
+	err := Registry.Setup()
+	var phaseErr *PhaseOrderError
+	if !errors.As(err, &phaseErr) || phaseErr.Method != "Setup" {
+		t.FailNow()
+	}
+
+	Registry.Create()
+	err = Registry.Assemble()
+	if err != nil {
+		t.FailNow()
+	}
+
+	rec.Reset()
+	err = Registry.Setup()
+	assertRecording(t, []string{"c.SetupAcorn", "fa.SetupErr"})
+	if !errors.Is(err, faila.ErrSetup) {
+		t.FailNow()
+	}
+	var lifecycleErr *LifecycleError
+	if !errors.As(err, &lifecycleErr) || lifecycleErr.Phase != PhaseSetup || lifecycleErr.AcornName != faila.FailAName {
+		t.FailNow()
+	}
+	if err.Error() != "error during setup of Acorn 'faila': faila setup failed" {
+		t.Errorf("unexpected error message: %s", err.Error())
+	}
+}
+
+func TestRegistry_ErrorTypes_Cycle(t *testing.T) {
+	Registry = New()
+
+	Registry.Register(circlea.New)
+	Registry.Register(circleb.New)
+	Registry.Create()
+	_ = Registry.Assemble()
+
+	err := Registry.Setup()
+	var cycleErr *CycleError
+	if !errors.As(err, &cycleErr) || cycleErr.Phase != PhaseSetup || cycleErr.AcornName != circleint.MockAName {
+		t.FailNow()
+	}
+	if cycleErr.Error() != "circular setup dependency mocka -[SetupAfter]-> mockb -[SetupAfter]-> mocka - not allowed" {
+		t.Errorf("unexpected error message: %s", cycleErr.Error())
+	}
+
+	err = Registry.Teardown()
+	if !errors.As(err, &cycleErr) || cycleErr.Phase != PhaseTeardown {
+		t.FailNow()
+	}
+	if cycleErr.Error() != "circular teardown dependency mocka -[TeardownAfter]-> mockb -[TeardownAfter]-> mocka - not allowed" {
+		t.Errorf("unexpected error message: %s", cycleErr.Error())
+	}
+}
+
+func TestRegistry_ErrorTypes_CycleWithOrderRule(t *testing.T) {
+	Registry = New()
+
+	Registry.Register(revcirclea.New)
+	Registry.Register(revcircleb.New)
+	Registry.Create()
+	_ = Registry.Assemble()
+
+	err := Registry.Setup()
+	var cycleErr *CycleError
+	if !errors.As(err, &cycleErr) {
+		t.FailNow()
+	}
+	expectedPath := []CycleStep{
+		{AcornName: revcircleint.RevCircleAName},
+		{AcornName: revcircleint.RevCircleBName, Via: EdgeSetupOrderRule},
+		{AcornName: revcircleint.RevCircleAName, Via: EdgeSetupAfter},
+	}
+	if fmt.Sprintf("%v", cycleErr.Path) != fmt.Sprintf("%v", expectedPath) {
+		t.Errorf("unexpected cycle path: %v", cycleErr.Path)
+	}
+}
+
+func TestRegistry_Teardown_ContinuesAfterFailure(t *testing.T) {
+	Registry = New()
+
+	Registry.Register(faila.New)
+	Registry.Register(mockb.New)
+	Registry.Register(mockc.New)
+	Registry.Create()
+	_ = Registry.Assemble()
+
+	Registry.SkipSetup(Registry.GetAcornByName(faila.FailAName))
+	err := Registry.Setup()
+	if err != nil {
+		t.FailNow()
+	}
+
+	rec.Reset()
+	err = Registry.Teardown()
+	// teardown of faila fails, but b and c are still torn down
+	assertRecording(t, []string{"c.TeardownAcorn", "b.TeardownAcorn", "fa.TeardownErr"})
+	if !errors.Is(err, faila.ErrTeardown) {
+		t.FailNow()
+	}
+	var lifecycleErr *LifecycleError
+	if !errors.As(err, &lifecycleErr) || lifecycleErr.Phase != PhaseTeardown || lifecycleErr.AcornName != faila.FailAName {
+		t.FailNow()
+	}
+}
+
+func TestRegistry_Teardown_FailureOfPrerequisiteReportedOnce(t *testing.T) {
+	Registry = New()
+
+	var afterErr error
+	Registry.Register(faila.New)
+	Registry.Register(mockc.New)
+	Registry.Register(hook.New(&hook.HookImpl{Name: "p1", OnTeardown: func(registry auacornapi.AcornRegistry) {
+		afterErr = registry.TeardownAfter(registry.GetAcornByName(faila.FailAName))
+		rec.Add("p1.TeardownAcorn")
+	}}))
+	Registry.Create()
+	_ = Registry.Assemble()
+
+	Registry.SkipSetup(Registry.GetAcornByName(faila.FailAName))
+	err := Registry.Setup()
+	if err != nil {
+		t.FailNow()
+	}
+
+	rec.Reset()
+	err = Registry.Teardown()
+	// p1 is not told about the failure of faila, and finishes its own teardown
+	assertRecording(t, []string{"fa.TeardownErr", "p1.TeardownAcorn", "c.TeardownAcorn"})
+	if afterErr != nil {
+		t.Errorf("unexpected error: %s", afterErr.Error())
+	}
+	if err == nil || err.Error() != "error during teardown of Acorn 'faila': faila teardown failed" {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestRegistry_RollbackOnSetupFailure(t *testing.T) {
+	Registry = New(WithRollbackOnSetupFailure())
+
+	Registry.Register(reversea.New)
+	Registry.Register(reverseb.New)
+	Registry.Register(faila.New)
+	Registry.Register(mockc.New)
+	Registry.Create()
+	_ = Registry.Assemble()
+
+	rec.Reset()
+	err := Registry.Setup()
+	// faila fails, then exactly the acorns that completed setup are torn down in reverse order of completion
+	assertRecording(t, []string{"b.SetupAcorn", "a.SetupAcorn", "c.SetupAcorn", "fa.SetupErr",
+		"c.TeardownAcorn", "a.TeardownAcorn", "b.TeardownAcorn"})
+	if !errors.Is(err, faila.ErrSetup) {
+		t.FailNow()
+	}
+
+	// everything has been cleaned up already
+	rec.Reset()
+	err = Registry.Teardown()
+	if err != nil {
+		t.FailNow()
+	}
+	assertRecording(t, []string{})
+}
+
+func TestRegistry_TeardownOrderRule(t *testing.T) {
+	Registry = New()
+
+	Registry.Register(reversea.New)
+	Registry.Register(reverseb.New)
+	Registry.Create()
+
+	a := Registry.GetAcornByName(reverseint.ReverseAName)
+	b := Registry.GetAcornByName(reverseint.ReverseBName)
+	// specifies: b must be torn down before a
+	err := Registry.AddTeardownOrderRule(b, a)
+	if err != nil {
+		t.FailNow()
+	}
+
+	_ = Registry.Assemble()
+	_ = Registry.Setup()
+
+	rec.Reset()
+	err = Registry.Teardown()
+	if err != nil {
+		t.FailNow()
+	}
+	assertRecording(t, []string{"b.TeardownAcorn", "a.TeardownAcorn"})
+}
+
+func TestRegistry_TeardownOrderRule_CycleDetection(t *testing.T) {
+	Registry = New()
+
+	Registry.Register(mocka.New)
+	Registry.Register(mockb.New)
+	Registry.Register(mockc.New)
+	Registry.Create()
+
+	a := Registry.GetAcornByName(mocka.MockAName)
+	c := Registry.GetAcornByName(mockc.MockCName)
+	// a already calls TeardownAfter(c), so this closes a circle
+	err := Registry.AddTeardownOrderRule(a, c)
+	if err != nil {
+		t.FailNow()
+	}
+
+	_ = Registry.Assemble()
+	_ = Registry.Setup()
+
+	err = Registry.Teardown()
+	var cycleErr *CycleError
+	if !errors.As(err, &cycleErr) {
+		t.FailNow()
+	}
+	if cycleErr.Error() != "circular teardown dependency mocka -[TeardownAfter]-> mockc -[AddTeardownOrderRule]-> mocka - not allowed" {
+		t.Errorf("unexpected error message: %s", cycleErr.Error())
+	}
+}
+
+func TestRegistry_Teardown_ReverseSetupOrder(t *testing.T) {
+	Registry = New()
+
+	Registry.Register(reverseb.New)
+	Registry.Register(reversea.New)
+	Registry.Create()
+	_ = Registry.Assemble()
+
+	rec.Reset()
+	err := Registry.Setup()
+	if err != nil {
+		t.FailNow()
+	}
+	assertRecording(t, []string{"b.SetupAcorn", "a.SetupAcorn"})
+
+	rec.Reset()
+	err = Registry.Teardown()
+	if err != nil {
+		t.FailNow()
+	}
+	// registration order would be b, a
+	assertRecording(t, []string{"a.TeardownAcorn", "b.TeardownAcorn"})
+}
+
+func TestRegistry_DeclaredDependencies(t *testing.T) {
+	Registry = New()
+
+	Registry.Register(declareda.New)
+	Registry.Register(declaredb.New)
+	Registry.Create()
+	err := Registry.Assemble()
+	if err != nil {
+		t.FailNow()
+	}
+
+	rec.Reset()
+	err = Registry.Setup()
+	if err != nil {
+		t.FailNow()
+	}
+	assertRecording(t, []string{"b.SetupAcorn", "a.SetupAcorn"})
+
+	rec.Reset()
+	err = Registry.Teardown()
+	if err != nil {
+		t.FailNow()
+	}
+	// reverse setup order would be a, b
+	assertRecording(t, []string{"b.TeardownAcorn", "a.TeardownAcorn"})
+}
+
+func TestRegistry_DeclaredDependencies_CycleDetectedUpFront(t *testing.T) {
+	Registry = New()
+
+	Registry.Register(declareda.New)
+	Registry.Register(declaredb.New)
+	Registry.Create()
+	Registry.CreateOverride(declaredb.DeclaredBName, &declaredb.DeclaredBImpl{SetupDeps: []string{declareda.DeclaredAName}})
+
+	rec.Reset()
+	err := Registry.Assemble()
+	var cycleErr *CycleError
+	if !errors.As(err, &cycleErr) {
+		t.FailNow()
+	}
+	if cycleErr.Error() != "circular setup dependency declareda -[SetupDependencies]-> declaredb -[SetupDependencies]-> declareda - not allowed" {
+		t.Errorf("unexpected error message: %s", cycleErr.Error())
+	}
+
+	err = Registry.Setup()
+	if err == nil {
+		t.FailNow()
+	}
+	// no setup was attempted
+	assertRecording(t, []string{"a.AssembleAcorn", "b.AssembleAcorn"})
+}
+
+func TestRegistry_DeclaredDependencies_Missing(t *testing.T) {
+	Registry = New()
+
+	Registry.Register(declareda.New)
+	Registry.Register(declaredb.New)
+	Registry.Create()
+	Registry.CreateOverride(declaredb.DeclaredBName, &declaredb.DeclaredBImpl{TeardownDeps: []string{"unknownz"}})
+
+	err := Registry.Assemble()
+	var missingErr *MissingAcornError
+	if !errors.As(err, &missingErr) || missingErr.Requester != declaredb.DeclaredBName || missingErr.AcornName != "unknownz" {
+		t.FailNow()
+	}
+}
+
+func TestRegistry_DeclaredDependencies_RetryAfterMissing(t *testing.T) {
+	Registry = New()
+
+	Registry.Register(declareda.New)
+	Registry.Register(declaredb.New)
+	Registry.Create()
+	Registry.CreateOverride(declaredb.DeclaredBName, &declaredb.DeclaredBImpl{TeardownDeps: []string{"unknownz"}})
+	if Registry.Assemble() == nil {
+		t.FailNow()
+	}
+
+	// the dependencies declared by the first attempt must not refer to the replaced instance
+	Registry.CreateOverride(declaredb.DeclaredBName, &declaredb.DeclaredBImpl{})
+	if err := Registry.Assemble(); err != nil {
+		t.Errorf("unexpected error: %s", err.Error())
+		t.FailNow()
+	}
+	rec.Reset()
+	if err := Registry.Setup(); err != nil {
+		t.Errorf("unexpected error: %s", err.Error())
+		t.FailNow()
+	}
+	assertRecording(t, []string{"b.SetupAcorn", "a.SetupAcorn"})
+}
+
+func TestRegistry_DeclaredDependencies_CallingBack(t *testing.T) {
+	Registry = New()
+
+	Registry.Register(declareda.New)
+	Registry.Register(declaredb.New)
+	Registry.Register(declaredc.New(&declaredc.DeclaredCImpl{Declare: func() []string {
+		// must not deadlock
+		return []string{Registry.GetAcornByName(declaredb.DeclaredBName).AcornName()}
+	}}))
+	Registry.Create()
+	if err := Registry.Assemble(); err != nil {
+		t.Errorf("unexpected error: %s", err.Error())
+		t.FailNow()
+	}
+
+	rec.Reset()
+	if err := Registry.Setup(); err != nil {
+		t.FailNow()
+	}
+	assertRecording(t, []string{"b.SetupAcorn", "a.SetupAcorn", "c.SetupAcorn"})
 }
@@ -0,0 +1,191 @@
+package auacorn
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	auacornapi "github.com/StephanHCB/go-autumn-acorn-registry/api"
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+)
+
+// Exit codes returned by Run.
+const (
+	// ExitOK means the application was set up, ran until asked to shut down, and was torn down cleanly.
+	ExitOK = 0
+
+	// ExitSetupFailed means Create, Assemble, Setup or Start failed. Whatever was set up has been torn down.
+	ExitSetupFailed = 1
+
+	// ExitTeardownFailed means Teardown reported errors. All Acorns have been given the chance to tear down.
+	ExitTeardownFailed = 2
+
+	// ExitShutdownTimeout means Teardown did not complete within the shutdown timeout.
+	ExitShutdownTimeout = 3
+
+	// ExitForced means a second signal arrived before Teardown completed, so Run gave up waiting for it.
+	ExitForced = 130
+)
+
+// RunOption configures Run and Main.
+type RunOption func(config *runConfig)
+
+type runConfig struct {
+	shutdownTimeout time.Duration
+	signals         []os.Signal
+	signalChannel   chan os.Signal // if set, used instead of subscribing to signals
+	errorHandler    func(err error)
+}
+
+// WithShutdownTimeout limits how long Teardown may take once shutdown has begun. Zero means no limit,
+// which is the default.
+func WithShutdownTimeout(timeout time.Duration) RunOption {
+	return func(config *runConfig) {
+		config.shutdownTimeout = timeout
+	}
+}
+
+// WithShutdownSignals sets the signals that make Run shut down the application.
+//
+// The default is SIGINT and SIGTERM.
+func WithShutdownSignals(signals ...os.Signal) RunOption {
+	return func(config *runConfig) {
+		config.signals = signals
+	}
+}
+
+// WithRunErrorHandler sets the callback that receives each error that makes Run return a non-zero exit code.
+//
+// The default handler writes the error to the standard logger.
+func WithRunErrorHandler(handler func(err error)) RunOption {
+	return func(config *runConfig) {
+		config.errorHandler = handler
+	}
+}
+
+func defaultRunErrorHandler(err error) {
+	log.Println("acorn registry: " + err.Error())
+}
+
+// Main runs the application built from the Acorns registered with registry, and exits the process
+// with the exit code returned by Run.
+//
+// Typical usage is to Register() all Acorns in your main(), then call Main(auacorn.Registry).
+func Main(registry auacornapi.AcornRegistry, options ...RunOption) {
+	os.Exit(Run(context.Background(), registry, options...))
+}
+
+// Run drives the complete lifecycle of the Acorns registered with registry, and returns an exit code.
+//
+// It calls Create(), Assemble(), Setup() and Start(), then waits until it receives one of the shutdown signals,
+// or until ctx is done, and finally calls Teardown(), which includes Stop(). If setup or start fails,
+// it tears down whatever was set up. A shutdown signal during setup aborts the setup.
+//
+// If a second signal arrives while tearing down, Run returns ExitForced without waiting any longer.
+//
+// registry must implement auacornapi.ExtendedAcornRegistry, like the registries provided by this library,
+// or else Run returns ExitSetupFailed right away.
+func Run(ctx context.Context, registry auacornapi.AcornRegistry, options ...RunOption) int {
+	config := &runConfig{
+		signals:      []os.Signal{syscall.SIGINT, syscall.SIGTERM},
+		errorHandler: defaultRunErrorHandler,
+	}
+	for _, option := range options {
+		option(config)
+	}
+
+	extended, ok := registry.(auacornapi.ExtendedAcornRegistry)
+	if !ok {
+		config.errorHandler(fmt.Errorf("registry of type %T does not implement ExtendedAcornRegistry", registry))
+		return ExitSetupFailed
+	}
+
+	signals := config.signalChannel
+	if signals == nil {
+		signals = make(chan os.Signal, 2)
+		signal.Notify(signals, config.signals...)
+		defer signal.Stop(signals)
+	}
+
+	// the first signal cancels the context, aborting the setup, or ending the wait
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	go func() {
+		select {
+		case <-signals:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	if err := registry.Create(); err != nil {
+		config.errorHandler(err)
+		return ExitSetupFailed
+	}
+	if err := extended.AssembleCtx(ctx); err != nil {
+		if aborted(ctx, err) {
+			// nothing was set up yet
+			return ExitOK
+		}
+		config.errorHandler(err)
+		return ExitSetupFailed
+	}
+	exitCode := ExitOK
+	if err := extended.SetupCtx(ctx); err != nil {
+		if !aborted(ctx, err) {
+			config.errorHandler(err)
+			exitCode = ExitSetupFailed
+		}
+	} else if err := extended.StartCtx(ctx); err != nil {
+		if !aborted(ctx, err) {
+			config.errorHandler(err)
+			exitCode = ExitSetupFailed
+		}
+	} else {
+		<-ctx.Done()
+	}
+
+	// from now on, signals are for shutdown() to see
+	cancel()
+	if teardownExitCode := shutdown(extended, config, signals); exitCode == ExitOK {
+		exitCode = teardownExitCode
+	}
+	return exitCode
+}
+
+// aborted tells whether err is the result of a shutdown request, rather than a failure.
+func aborted(ctx context.Context, err error) bool {
+	return ctx.Err() != nil && errors.Is(err, context.Canceled)
+}
+
+func shutdown(registry auacornapi.ExtendedAcornRegistry, config *runConfig, signals chan os.Signal) int {
+	ctx := context.Background()
+	if config.shutdownTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, config.shutdownTimeout)
+		defer cancel()
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		done <- registry.TeardownCtx(ctx)
+	}()
+
+	select {
+	case err := <-done:
+		if err != nil {
+			config.errorHandler(err)
+			return ExitTeardownFailed
+		}
+		return ExitOK
+	case <-ctx.Done():
+		config.errorHandler(errors.New("teardown did not complete within the shutdown timeout"))
+		return ExitShutdownTimeout
+	case <-signals:
+		config.errorHandler(errors.New("teardown aborted by second signal"))
+		return ExitForced
+	}
+}
@@ -0,0 +1,140 @@
+package auacorn
+
+import (
+	"context"
+	auacornapi "github.com/StephanHCB/go-autumn-acorn-registry/api"
+	"github.com/StephanHCB/go-autumn-acorn-registry/test/faila"
+	"github.com/StephanHCB/go-autumn-acorn-registry/test/mocka"
+	"github.com/StephanHCB/go-autumn-acorn-registry/test/mockb"
+	"github.com/StephanHCB/go-autumn-acorn-registry/test/mockc"
+	"github.com/StephanHCB/go-autumn-acorn-registry/test/para"
+	"github.com/StephanHCB/go-autumn-acorn-registry/test/rec"
+	"os"
+	"testing"
+	"time"
+)
+
+func withSignalChannel(signals chan os.Signal) RunOption {
+	return func(config *runConfig) {
+		config.signalChannel = signals
+	}
+}
+
+func ignoreRunErrors(_ error) {}
+
+// releaseStuckTeardown lets a blocked teardown continue, and waits for it, so it does not disturb other tests.
+func releaseStuckTeardown(t *testing.T, block chan struct{}, name string) {
+	close(block)
+	deadline := time.Now().Add(time.Second)
+	for countRecorded(name+".TeardownAcorn") == 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("stuck teardown did not complete")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+// sendSignals returns a para acorn that sends count signals once it is set up.
+func sendSignals(signals chan os.Signal, count int) auacornapi.Constructor {
+	return para.New(&para.ParaImpl{Name: "signaller", OnSetup: func() {
+		for i := 0; i < count; i++ {
+			signals <- os.Interrupt
+		}
+	}})
+}
+
+func TestRun_ShutdownOnSignal(t *testing.T) {
+	Registry = New()
+
+	signals := make(chan os.Signal, 2)
+	Registry.Register(mocka.New)
+	Registry.Register(mockb.New)
+	Registry.Register(mockc.New)
+	Registry.Register(sendSignals(signals, 1))
+
+	rec.Reset()
+	if Run(context.Background(), Registry, withSignalChannel(signals)) != ExitOK {
+		t.FailNow()
+	}
+	recording := rec.Get()
+	if recording[len(recording)-1] != "b.TeardownAcorn" {
+		t.Errorf("unexpected recording: %v", recording)
+	}
+}
+
+func TestRun_ShutdownOnContext(t *testing.T) {
+	Registry = New()
+
+	Registry.Register(mockc.New)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if Run(ctx, Registry, withSignalChannel(make(chan os.Signal))) != ExitOK {
+		t.FailNow()
+	}
+}
+
+func TestRun_SetupFailed(t *testing.T) {
+	Registry = New()
+
+	Registry.Register(mockc.New)
+	Registry.Register(faila.New)
+
+	rec.Reset()
+	exitCode := Run(context.Background(), Registry, withSignalChannel(make(chan os.Signal)), WithRunErrorHandler(ignoreRunErrors))
+	if exitCode != ExitSetupFailed {
+		t.FailNow()
+	}
+	// the registry is torn down without waiting for a signal
+	assertRecording(t, []string{"c.New", "fa.New", "c.AssembleAcorn", "fa.AssembleAcorn", "c.SetupAcorn", "fa.SetupErr",
+		"c.TeardownAcorn", "fa.TeardownErr"})
+}
+
+func TestRun_RegistryWithoutExtensions(t *testing.T) {
+	// only implements AcornRegistry, like a mock would
+	registry := struct{ auacornapi.AcornRegistry }{New()}
+
+	var reported error
+	exitCode := Run(context.Background(), registry, withSignalChannel(make(chan os.Signal)),
+		WithRunErrorHandler(func(err error) { reported = err }))
+	if exitCode != ExitSetupFailed || reported == nil {
+		t.FailNow()
+	}
+}
+
+func TestRun_ShutdownTimeout(t *testing.T) {
+	Registry = New()
+
+	block := make(chan struct{})
+	Registry.Register(para.New(&para.ParaImpl{Name: "stuck", TeardownBlock: block}))
+
+	signals := make(chan os.Signal, 1)
+	Registry.Register(sendSignals(signals, 1))
+
+	rec.Reset()
+	start := time.Now()
+	exitCode := Run(context.Background(), Registry, withSignalChannel(signals), WithShutdownTimeout(20*time.Millisecond),
+		WithRunErrorHandler(ignoreRunErrors))
+	if exitCode != ExitShutdownTimeout || time.Since(start) > 500*time.Millisecond {
+		t.Fail()
+	}
+	releaseStuckTeardown(t, block, "stuck")
+}
+
+func TestRun_SecondSignalForcesExit(t *testing.T) {
+	Registry = New()
+
+	block := make(chan struct{})
+	Registry.Register(para.New(&para.ParaImpl{Name: "stuck", TeardownBlock: block}))
+
+	signals := make(chan os.Signal, 2)
+	Registry.Register(sendSignals(signals, 2))
+
+	rec.Reset()
+	start := time.Now()
+	exitCode := Run(context.Background(), Registry, withSignalChannel(signals), WithRunErrorHandler(ignoreRunErrors))
+	if exitCode != ExitForced || time.Since(start) > 500*time.Millisecond {
+		t.Fail()
+	}
+	releaseStuckTeardown(t, block, "stuck")
+}
@@ -0,0 +1,211 @@
+package auacorn
+
+import (
+	"context"
+	auacornapi "github.com/StephanHCB/go-autumn-acorn-registry/api"
+)
+
+// acornScope is what an Acorn gets passed as its registry in AssembleAcorn, SetupAcorn and TeardownAcorn.
+//
+// It forwards everything to the registry, but lets it know which Acorn is calling. This is how SetupAfter
+// and TeardownAfter know who is waiting for whom, even if several Acorns are processed concurrently,
+// and how strict mode knows which lifecycle method the call came from.
+//
+// It also carries the Acorn's context, so SetupAfter and TeardownAfter respect its deadline, and measures
+// the time spent in them, so it can be excluded from the Acorn's own timing.
+type acornScope struct {
+	*AcornRegistryImpl
+	acorn  auacornapi.Acorn
+	phase  Phase
+	ctx    context.Context
+	nested *nestedClock
+}
+
+func (s *acornScope) GetAcornByName(acornName string) auacornapi.Acorn {
+	_ = s.violation("GetAcornByName")
+	return s.guarded(s.acorn, s.getAcornByName(s.acorn, acornName))
+}
+
+func (s *acornScope) SetupAfter(otherAcorn auacornapi.Acorn) error {
+	if err := s.violation("SetupAfter"); err != nil {
+		return err
+	}
+	s.nested.enter()
+	defer s.nested.leave()
+	return s.setupAfter(s.ctx, s.acorn, otherAcorn, EdgeSetupAfter)
+}
+
+func (s *acornScope) SetupAfterCtx(ctx context.Context, otherAcorn auacornapi.Acorn) error {
+	if err := s.violation("SetupAfterCtx"); err != nil {
+		return err
+	}
+	s.nested.enter()
+	defer s.nested.leave()
+	return s.setupAfter(ctx, s.acorn, otherAcorn, EdgeSetupAfter)
+}
+
+func (s *acornScope) TeardownAfter(otherAcorn auacornapi.Acorn) error {
+	if err := s.violation("TeardownAfter"); err != nil {
+		return err
+	}
+	s.nested.enter()
+	defer s.nested.leave()
+	return s.teardownAfter(s.ctx, s.acorn, otherAcorn, EdgeTeardownAfter)
+}
+
+func (s *acornScope) TeardownAfterCtx(ctx context.Context, otherAcorn auacornapi.Acorn) error {
+	if err := s.violation("TeardownAfterCtx"); err != nil {
+		return err
+	}
+	s.nested.enter()
+	defer s.nested.leave()
+	return s.teardownAfter(ctx, s.acorn, otherAcorn, EdgeTeardownAfter)
+}
+
+func (s *acornScope) AddSetupOrderRule(prerequisite auacornapi.Acorn, dependency auacornapi.Acorn) error {
+	if err := s.violation("AddSetupOrderRule"); err != nil {
+		return err
+	}
+	return s.AcornRegistryImpl.AddSetupOrderRule(prerequisite, dependency)
+}
+
+func (s *acornScope) AddTeardownOrderRule(first auacornapi.Acorn, then auacornapi.Acorn) error {
+	if err := s.violation("AddTeardownOrderRule"); err != nil {
+		return err
+	}
+	return s.AcornRegistryImpl.AddTeardownOrderRule(first, then)
+}
+
+// --- methods meant for the application, which Acorns must not call, see WithStrictMode() ---
+
+func (s *acornScope) Register(constructor auacornapi.Constructor) {
+	_ = s.violation("Register")
+	s.AcornRegistryImpl.Register(constructor)
+}
+
+func (s *acornScope) RegisterOverride(constructor auacornapi.Constructor) {
+	_ = s.violation("RegisterOverride")
+	s.AcornRegistryImpl.RegisterOverride(constructor)
+}
+
+func (s *acornScope) RegisterIf(predicate func() bool, constructor auacornapi.Constructor) {
+	_ = s.violation("RegisterIf")
+	s.AcornRegistryImpl.RegisterIf(predicate, constructor)
+}
+
+func (s *acornScope) RegisterFor(profile string, constructor auacornapi.Constructor) {
+	_ = s.violation("RegisterFor")
+	s.AcornRegistryImpl.RegisterFor(profile, constructor)
+}
+
+func (s *acornScope) ActivateProfiles(profiles ...string) {
+	_ = s.violation("ActivateProfiles")
+	s.AcornRegistryImpl.ActivateProfiles(profiles...)
+}
+
+func (s *acornScope) RegisterLazy(acornName string, constructor auacornapi.Constructor) {
+	_ = s.violation("RegisterLazy")
+	s.AcornRegistryImpl.RegisterLazy(acornName, constructor)
+}
+
+func (s *acornScope) RegisterGuardAdapter(acornName string, adapter auacornapi.GuardAdapter) {
+	_ = s.violation("RegisterGuardAdapter")
+	s.AcornRegistryImpl.RegisterGuardAdapter(acornName, adapter)
+}
+
+func (s *acornScope) Create() error {
+	if err := s.violation("Create"); err != nil {
+		return err
+	}
+	return s.AcornRegistryImpl.Create()
+}
+
+func (s *acornScope) Assemble() error {
+	if err := s.violation("Assemble"); err != nil {
+		return err
+	}
+	return s.AcornRegistryImpl.Assemble()
+}
+
+func (s *acornScope) AssembleCtx(ctx context.Context) error {
+	if err := s.violation("AssembleCtx"); err != nil {
+		return err
+	}
+	return s.AcornRegistryImpl.AssembleCtx(ctx)
+}
+
+func (s *acornScope) Setup() error {
+	if err := s.violation("Setup"); err != nil {
+		return err
+	}
+	return s.AcornRegistryImpl.Setup()
+}
+
+func (s *acornScope) SetupCtx(ctx context.Context) error {
+	if err := s.violation("SetupCtx"); err != nil {
+		return err
+	}
+	return s.AcornRegistryImpl.SetupCtx(ctx)
+}
+
+func (s *acornScope) Start() error {
+	if err := s.violation("Start"); err != nil {
+		return err
+	}
+	return s.AcornRegistryImpl.Start()
+}
+
+func (s *acornScope) StartCtx(ctx context.Context) error {
+	if err := s.violation("StartCtx"); err != nil {
+		return err
+	}
+	return s.AcornRegistryImpl.StartCtx(ctx)
+}
+
+func (s *acornScope) Stop() error {
+	if err := s.violation("Stop"); err != nil {
+		return err
+	}
+	return s.AcornRegistryImpl.Stop()
+}
+
+func (s *acornScope) StopCtx(ctx context.Context) error {
+	if err := s.violation("StopCtx"); err != nil {
+		return err
+	}
+	return s.AcornRegistryImpl.StopCtx(ctx)
+}
+
+func (s *acornScope) Teardown() error {
+	if err := s.violation("Teardown"); err != nil {
+		return err
+	}
+	return s.AcornRegistryImpl.Teardown()
+}
+
+func (s *acornScope) TeardownCtx(ctx context.Context) error {
+	if err := s.violation("TeardownCtx"); err != nil {
+		return err
+	}
+	return s.AcornRegistryImpl.TeardownCtx(ctx)
+}
+
+func (s *acornScope) CreateOverride(name string, instance auacornapi.Acorn) {
+	_ = s.violation("CreateOverride")
+	s.AcornRegistryImpl.CreateOverride(name, instance)
+}
+
+func (s *acornScope) SkipAssemble(instance auacornapi.Acorn) {
+	_ = s.violation("SkipAssemble")
+	s.AcornRegistryImpl.SkipAssemble(instance)
+}
+
+func (s *acornScope) SkipSetup(instance auacornapi.Acorn) {
+	_ = s.violation("SkipSetup")
+	s.AcornRegistryImpl.SkipSetup(instance)
+}
+
+func (s *acornScope) SkipTeardown(instance auacornapi.Acorn) {
+	_ = s.violation("SkipTeardown")
+	s.AcornRegistryImpl.SkipTeardown(instance)
+}
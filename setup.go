@@ -0,0 +1,273 @@
+package auacorn
+
+import (
+	"context"
+	"errors"
+	auacornapi "github.com/StephanHCB/go-autumn-acorn-registry/api"
+)
+
+// SkipSetup lets you mark an instance as already set up, so it will be skipped during Setup().
+//
+// useful for testing
+func (a *AcornRegistryImpl) SkipSetup(instance auacornapi.Acorn) {
+	_ = a.violation("SkipSetup")
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.phaseByInstance[instance] = phaseSetupDone
+}
+
+// Setup sets up all acorns, either one by one in iteration order, or concurrently, see WithParallelSetup().
+func (a *AcornRegistryImpl) Setup() error {
+	return a.SetupCtx(context.Background())
+}
+
+// SetupCtx is Setup, but stops setting up acorns once ctx is done.
+func (a *AcornRegistryImpl) SetupCtx(ctx context.Context) (err error) {
+	ctx, cancel := a.withPhaseTimeout(ctx)
+	defer cancel()
+
+	a.mu.Lock()
+	if a.phase != phaseAssembleDone {
+		a.mu.Unlock()
+		return &PhaseOrderError{Method: "Setup", Detail: "must come after Assemble()"}
+	}
+	a.setupRun = newPhaseRun(PhaseSetup)
+	a.setupDependents = make(map[auacornapi.Acorn][]auacornapi.Acorn)
+	a.mu.Unlock()
+
+	began := a.notifyBefore(PhaseSetup, "")
+	defer func() { a.notifyAfter(PhaseSetup, "", began, err) }()
+
+	if a.parallelSetup {
+		err = a.runConcurrently(a.setupRun, a.setupWorkers, true, a.claimReadyForSetup, func(instance auacornapi.Acorn) error {
+			return a.runSetup(ctx, instance)
+		})
+	} else {
+		err = a.setupOneByOne(ctx)
+	}
+	if err != nil {
+		if a.rollbackOnSetupFailure {
+			return errors.Join(err, a.rollback())
+		}
+		return err
+	}
+
+	a.mu.Lock()
+	a.phase = phaseSetupDone
+	a.mu.Unlock()
+	return nil
+}
+
+func (a *AcornRegistryImpl) setupOneByOne(ctx context.Context) error {
+	instances := a.instancesInPhase(phaseAssembleDone)
+	a.mu.Lock()
+	instances = a.eagerFirst(instances)
+	a.mu.Unlock()
+
+	for _, instance := range instances {
+		a.mu.Lock()
+		if a.phaseByInstance[instance] != phaseAssembleDone {
+			// only do the phase if it hasn't already been done
+			a.mu.Unlock()
+			continue
+		}
+		a.claimForSetup(instance)
+		a.mu.Unlock()
+
+		if err := a.runSetup(ctx, instance); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// claimReadyForSetup claims up to limit acorns whose prerequisites known up front are all set up,
+// in iteration order. A negative limit means no limit. Caller must hold the lock.
+func (a *AcornRegistryImpl) claimReadyForSetup(limit int) []auacornapi.Acorn {
+	eagerPending := false
+	for _, name := range a.orderedNames() {
+		instance := a.instancesByName[name]
+		phase := a.phaseByInstance[instance]
+		if !a.lazyInstances[instance] && (phase == phaseAssembleDone || phase == phaseInRecursiveSetup) {
+			eagerPending = true
+		}
+	}
+
+	result := make([]auacornapi.Acorn, 0)
+	for _, name := range a.orderedNames() {
+		if limit >= 0 && len(result) >= limit {
+			break
+		}
+		instance := a.instancesByName[name]
+		if a.lazyInstances[instance] && eagerPending {
+			// lazy acorns are only set up on request, or after all others
+			continue
+		}
+		if a.phaseByInstance[instance] == phaseAssembleDone && a.prerequisitesSetUp(instance) {
+			a.claimForSetup(instance)
+			result = append(result, instance)
+		}
+	}
+	return result
+}
+
+// prerequisitesSetUp tells whether all prerequisites of instance known up front are set up.
+// Caller must hold the lock.
+func (a *AcornRegistryImpl) prerequisitesSetUp(instance auacornapi.Acorn) bool {
+	for _, prerequisite := range a.setupBefore[instance] {
+		phase := a.phaseByInstance[prerequisite.instance]
+		if phase == phaseAssembleDone || phase == phaseInRecursiveSetup {
+			return false
+		}
+	}
+	return true
+}
+
+// claimForSetup marks an instance as being set up, so nobody else starts setting it up. Caller must hold the lock.
+func (a *AcornRegistryImpl) claimForSetup(instance auacornapi.Acorn) {
+	a.phaseByInstance[instance] = phaseInRecursiveSetup
+	a.setupRun.claim(instance)
+}
+
+// runSetup sets up a claimed instance in the current goroutine, and lets everyone waiting for it know the outcome.
+func (a *AcornRegistryImpl) runSetup(ctx context.Context, instance auacornapi.Acorn) error {
+	err := a.injectExtraSetupAfterCallsThenSetup(ctx, instance)
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if err != nil {
+		err = wrapLifecycleError(PhaseSetup, instance, err)
+		if a.rollbackOnSetupFailure {
+			// the rollback must not tear down a partially set up instance
+			a.phaseByInstance[instance] = phaseSetupFailed
+		} else {
+			// without rollback, Teardown() still gets to clean up a partially set up instance
+			a.phaseByInstance[instance] = phaseSetupDone
+		}
+	} else {
+		a.phaseByInstance[instance] = phaseSetupDone
+		a.setupCompleted = append(a.setupCompleted, instance)
+	}
+	a.setupRun.finish(instance, err)
+	return err
+}
+
+func (a *AcornRegistryImpl) injectExtraSetupAfterCallsThenSetup(ctx context.Context, instance auacornapi.Acorn) error {
+	a.mu.Lock()
+	extraPrerequisites := a.setupBefore[instance]
+	a.mu.Unlock()
+	for _, prerequisite := range extraPrerequisites {
+		err := a.setupAfter(ctx, instance, prerequisite.instance, prerequisite.via)
+		if err != nil {
+			return err
+		}
+	}
+	return a.callAcorn(ctx, PhaseSetup, instance)
+}
+
+// rollback tears down exactly the acorns that completed setup, in reverse order of completion.
+//
+// It does not use the context of the failed setup, which may well be what made it fail.
+func (a *AcornRegistryImpl) rollback() error {
+	ctx, cancel := a.withPhaseTimeout(context.Background())
+	defer cancel()
+
+	a.mu.Lock()
+	a.teardownRun = newPhaseRun(PhaseTeardown)
+	a.teardownFailures = make([]error, 0)
+	completed := make([]auacornapi.Acorn, len(a.setupCompleted))
+	copy(completed, a.setupCompleted)
+	a.mu.Unlock()
+
+	for i := len(completed) - 1; i >= 0; i-- {
+		a.teardownTopLevel(ctx, completed[i])
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.phase = phaseTeardownDone
+	return errors.Join(a.teardownFailures...)
+}
+
+func (a *AcornRegistryImpl) SetupAfter(otherAcorn auacornapi.Acorn) error {
+	return a.setupAfter(context.Background(), nil, otherAcorn, EdgeSetupAfter)
+}
+
+// SetupAfterCtx is SetupAfter, but gives up once ctx is done.
+func (a *AcornRegistryImpl) SetupAfterCtx(ctx context.Context, otherAcorn auacornapi.Acorn) error {
+	return a.setupAfter(ctx, nil, otherAcorn, EdgeSetupAfter)
+}
+
+// setupAfter makes sure otherAcorn is set up before returning.
+//
+// If nobody is setting it up yet, it is set up in the current goroutine. If another goroutine is already setting
+// it up, we wait for it to finish, unless requester is (directly or indirectly) what it is waiting for.
+//
+// requester is nil if not called on the registry passed to an acorn, which is rejected, as we could neither
+// detect cycles nor tell apart several goroutines waiting on behalf of the same acorn.
+func (a *AcornRegistryImpl) setupAfter(ctx context.Context, requester auacornapi.Acorn, otherAcorn auacornapi.Acorn, via EdgeKind) error {
+	a.mu.Lock()
+	if a.phase != phaseAssembleDone || a.setupRun == nil {
+		a.mu.Unlock()
+		return &PhaseOrderError{Method: "SetupAfter", Detail: "only allowed during setup phase"}
+	}
+	if requester == nil {
+		a.mu.Unlock()
+		return &UnscopedCallError{Method: "SetupAfter"}
+	}
+	run := a.setupRun
+	otherAcorn = a.unguarded(otherAcorn)
+	a.observeEdge(requester.AcornName(), otherAcorn.AcornName(), via)
+
+	switch a.phaseByInstance[otherAcorn] {
+	case phaseAssembleDone:
+		a.claimForSetup(otherAcorn)
+		a.recordSetupEdge(requester, otherAcorn)
+		run.addWait(requester, otherAcorn, via)
+		a.mu.Unlock()
+
+		err := a.runSetup(ctx, otherAcorn)
+
+		a.mu.Lock()
+		run.removeWait(requester, otherAcorn)
+		a.mu.Unlock()
+		return err
+	case phaseInRecursiveSetup:
+		if cycle := run.findWaitCycle(requester, otherAcorn, via); cycle != nil {
+			a.mu.Unlock()
+			return cycle
+		}
+		a.recordSetupEdge(requester, otherAcorn)
+		run.addWait(requester, otherAcorn, via)
+		done := run.done[otherAcorn]
+		a.mu.Unlock()
+
+		var err error
+		select {
+		case <-done:
+		case <-ctx.Done():
+			err = &TimeoutError{Phase: PhaseSetup, AcornName: otherAcorn.AcornName(), Err: ctx.Err()}
+		}
+
+		a.mu.Lock()
+		defer a.mu.Unlock()
+		run.removeWait(requester, otherAcorn)
+		if err != nil {
+			return err
+		}
+		return run.errors[otherAcorn]
+	default:
+		// was already set up (or failed to), that is ok
+		defer a.mu.Unlock()
+		a.recordSetupEdge(requester, otherAcorn)
+		return run.errors[otherAcorn]
+	}
+}
+
+// recordSetupEdge remembers that requester was set up after otherAcorn, so it must be torn down before it.
+// Caller must hold the lock.
+func (a *AcornRegistryImpl) recordSetupEdge(requester auacornapi.Acorn, otherAcorn auacornapi.Acorn) {
+	if requester != nil {
+		a.setupDependents[otherAcorn] = append(a.setupDependents[otherAcorn], requester)
+	}
+}
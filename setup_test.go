@@ -0,0 +1,195 @@
+package auacorn
+
+import (
+	"errors"
+	auacornapi "github.com/StephanHCB/go-autumn-acorn-registry/api"
+	"github.com/StephanHCB/go-autumn-acorn-registry/test/hook"
+	"github.com/StephanHCB/go-autumn-acorn-registry/test/para"
+	"github.com/StephanHCB/go-autumn-acorn-registry/test/rec"
+	"sync"
+	"testing"
+	"time"
+)
+
+func countRecorded(entry string) int {
+	count := 0
+	for _, recorded := range rec.Get() {
+		if recorded == entry {
+			count++
+		}
+	}
+	return count
+}
+
+func indexRecorded(entry string) int {
+	for i, recorded := range rec.Get() {
+		if recorded == entry {
+			return i
+		}
+	}
+	return -1
+}
+
+func TestSetup_Parallel_IndependentAcornsRunConcurrently(t *testing.T) {
+	Registry = New(WithParallelSetup(0))
+
+	barrier := &sync.WaitGroup{}
+	barrier.Add(3)
+	Registry.Register(para.New(&para.ParaImpl{Name: "x", Barrier: barrier}))
+	Registry.Register(para.New(&para.ParaImpl{Name: "y", Barrier: barrier}))
+	Registry.Register(para.New(&para.ParaImpl{Name: "z", Barrier: barrier}))
+	Registry.Create()
+	_ = Registry.Assemble()
+
+	rec.Reset()
+	// would time out at the barrier if the acorns were set up one by one
+	err := Registry.Setup()
+	if err != nil {
+		t.Errorf("unexpected error: %s", err.Error())
+	}
+	if len(rec.Get()) != 6 {
+		t.Errorf("unexpected recording: %v", rec.Get())
+	}
+}
+
+func TestSetup_Parallel_WorkerLimit(t *testing.T) {
+	Registry = New(WithParallelSetup(1))
+
+	Registry.Register(para.New(&para.ParaImpl{Name: "x", Delay: 10 * time.Millisecond}))
+	Registry.Register(para.New(&para.ParaImpl{Name: "y", Delay: 10 * time.Millisecond}))
+	Registry.Register(para.New(&para.ParaImpl{Name: "z", Delay: 10 * time.Millisecond}))
+	Registry.Create()
+	_ = Registry.Assemble()
+
+	rec.Reset()
+	err := Registry.Setup()
+	if err != nil {
+		t.FailNow()
+	}
+	// a single worker never interleaves
+	assertRecording(t, []string{"x.PreSetupAcorn", "x.SetupAcorn", "y.PreSetupAcorn", "y.SetupAcorn", "z.PreSetupAcorn", "z.SetupAcorn"})
+}
+
+func TestSetup_Parallel_DeclaredDependencies(t *testing.T) {
+	Registry = New(WithParallelSetup(0))
+
+	Registry.Register(para.New(&para.ParaImpl{Name: "x", SetupDeps: []string{"y"}}))
+	Registry.Register(para.New(&para.ParaImpl{Name: "y", Delay: 20 * time.Millisecond}))
+	Registry.Create()
+	_ = Registry.Assemble()
+
+	rec.Reset()
+	err := Registry.Setup()
+	if err != nil {
+		t.FailNow()
+	}
+	// x is not even started before y is done
+	assertRecording(t, []string{"y.PreSetupAcorn", "y.SetupAcorn", "x.PreSetupAcorn", "x.SetupAcorn"})
+}
+
+func TestSetup_Parallel_SetupAfterSameAcornOnlyOnce(t *testing.T) {
+	Registry = New(WithParallelSetup(0))
+
+	Registry.Register(para.New(&para.ParaImpl{Name: "x", SetupAfterNames: []string{"z"}}))
+	Registry.Register(para.New(&para.ParaImpl{Name: "y", SetupAfterNames: []string{"z"}}))
+	Registry.Register(para.New(&para.ParaImpl{Name: "z", Delay: 50 * time.Millisecond}))
+	Registry.Create()
+	_ = Registry.Assemble()
+
+	rec.Reset()
+	err := Registry.Setup()
+	if err != nil {
+		t.FailNow()
+	}
+	if countRecorded("z.PreSetupAcorn") != 1 || countRecorded("z.SetupAcorn") != 1 {
+		t.Errorf("z was not set up exactly once: %v", rec.Get())
+	}
+	if indexRecorded("z.SetupAcorn") > indexRecorded("x.SetupAcorn") || indexRecorded("z.SetupAcorn") > indexRecorded("y.SetupAcorn") {
+		t.Errorf("SetupAfter returned before z was set up: %v", rec.Get())
+	}
+}
+
+func TestSetup_Parallel_CycleAcrossGoroutines(t *testing.T) {
+	Registry = New(WithParallelSetup(0))
+
+	Registry.Register(para.New(&para.ParaImpl{Name: "x", SetupAfterNames: []string{"y"}}))
+	Registry.Register(para.New(&para.ParaImpl{Name: "y", SetupAfterNames: []string{"x"}}))
+	Registry.Create()
+	_ = Registry.Assemble()
+
+	result := make(chan error)
+	go func() {
+		result <- Registry.Setup()
+	}()
+	select {
+	case err := <-result:
+		var cycleErr *CycleError
+		if !errors.As(err, &cycleErr) || cycleErr.Phase != PhaseSetup || len(cycleErr.Path) != 3 {
+			t.Errorf("expected cycle error, got: %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("deadlock in setup")
+	}
+}
+
+func TestSetup_GlobalRegistryFromSpawnedGoroutines(t *testing.T) {
+	Registry = New()
+
+	var scoped, global [2]error
+	Registry.Register(hook.New(&hook.HookImpl{Name: "x", OnSetup: func(registry auacornapi.AcornRegistry) {
+		slow := registry.GetAcornByName("slow")
+		wg := &sync.WaitGroup{}
+		for i := range scoped {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				scoped[i] = registry.SetupAfter(slow)
+				global[i] = Registry.SetupAfter(slow)
+			}(i)
+		}
+		wg.Wait()
+	}}))
+	Registry.Register(para.New(&para.ParaImpl{Name: "slow", Delay: 20 * time.Millisecond}))
+	Registry.Create()
+	_ = Registry.Assemble()
+
+	if Registry.Setup() != nil {
+		t.FailNow()
+	}
+	for i := range scoped {
+		var unscopedErr *UnscopedCallError
+		if scoped[i] != nil || !errors.As(global[i], &unscopedErr) {
+			t.Errorf("unexpected errors: %v, %v", scoped[i], global[i])
+		}
+	}
+}
+
+func TestSetup_Parallel_CycleThroughGlobalRegistry(t *testing.T) {
+	Registry = New(WithParallelSetup(0))
+
+	errs := make(chan error, 2)
+	Registry.Register(hook.New(&hook.HookImpl{Name: "h1", OnSetup: func(_ auacornapi.AcornRegistry) {
+		errs <- Registry.SetupAfter(Registry.GetAcornByName("h2"))
+	}}))
+	Registry.Register(hook.New(&hook.HookImpl{Name: "h2", OnSetup: func(_ auacornapi.AcornRegistry) {
+		errs <- Registry.SetupAfter(Registry.GetAcornByName("h1"))
+	}}))
+	Registry.Create()
+	_ = Registry.Assemble()
+
+	result := make(chan error)
+	go func() {
+		result <- Registry.Setup()
+	}()
+	select {
+	case <-result:
+		for range []int{1, 2} {
+			var unscopedErr *UnscopedCallError
+			if err := <-errs; !errors.As(err, &unscopedErr) {
+				t.Errorf("expected unscoped call error, got: %v", err)
+			}
+		}
+	case <-time.After(time.Second):
+		t.Fatal("deadlock in setup")
+	}
+}
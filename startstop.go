@@ -0,0 +1,118 @@
+package auacorn
+
+import (
+	"context"
+	"errors"
+	auacornapi "github.com/StephanHCB/go-autumn-acorn-registry/api"
+)
+
+// Start starts all acorns that implement StartableAcorn, in order of setup completion.
+func (a *AcornRegistryImpl) Start() error {
+	return a.StartCtx(context.Background())
+}
+
+// StartCtx is Start, but stops starting acorns once ctx is done.
+//
+// Stops at the first failure. Acorns that were already started are stopped by Stop() or Teardown().
+func (a *AcornRegistryImpl) StartCtx(ctx context.Context) (err error) {
+	ctx, cancel := a.withPhaseTimeout(ctx)
+	defer cancel()
+
+	a.mu.Lock()
+	if a.phase != phaseSetupDone {
+		a.mu.Unlock()
+		return &PhaseOrderError{Method: "Start", Detail: "must come after Setup()"}
+	}
+	a.phase = phaseStartDone
+	a.started = make([]auacornapi.Acorn, 0)
+	order := a.startOrder()
+	a.mu.Unlock()
+
+	began := a.notifyBefore(PhaseStart, "")
+	defer func() { a.notifyAfter(PhaseStart, "", began, err) }()
+
+	for _, instance := range order {
+		if _, ok := instance.(auacornapi.StartableAcorn); !ok {
+			continue
+		}
+		if err := a.callAcorn(ctx, PhaseStart, instance); err != nil {
+			return wrapLifecycleError(PhaseStart, instance, err)
+		}
+		a.mu.Lock()
+		a.started = append(a.started, instance)
+		a.mu.Unlock()
+	}
+	return nil
+}
+
+// startOrder is the order of setup completion, followed by any instances that were set up without
+// the registry knowing when (e.g. due to SkipSetup), in iteration order. Caller must hold the lock.
+func (a *AcornRegistryImpl) startOrder() []auacornapi.Acorn {
+	result := make([]auacornapi.Acorn, 0, len(a.names))
+	seen := make(map[auacornapi.Acorn]bool)
+	for _, instance := range a.setupCompleted {
+		result = append(result, instance)
+		seen[instance] = true
+	}
+	for _, name := range a.orderedNames() {
+		instance := a.instancesByName[name]
+		if !seen[instance] && a.phaseByInstance[instance] == phaseSetupDone {
+			result = append(result, instance)
+		}
+	}
+	return result
+}
+
+// Stop stops all acorns that implement StoppableAcorn, in reverse order of setup completion, except those
+// that also implement StartableAcorn but were not started.
+func (a *AcornRegistryImpl) Stop() error {
+	return a.StopCtx(context.Background())
+}
+
+// StopCtx is Stop, but stops stopping acorns once ctx is done. Each acorn that is left running
+// is reported with a TimeoutError.
+//
+// Like Teardown(), it continues after failures, and returns all of them joined.
+func (a *AcornRegistryImpl) StopCtx(ctx context.Context) error {
+	ctx, cancel := a.withPhaseTimeout(ctx)
+	defer cancel()
+
+	a.mu.Lock()
+	if a.phase != phaseStartDone {
+		a.mu.Unlock()
+		return &PhaseOrderError{Method: "Stop", Detail: "must come after Start()"}
+	}
+	a.mu.Unlock()
+	return a.stop(ctx)
+}
+
+func (a *AcornRegistryImpl) stop(ctx context.Context) (err error) {
+	a.mu.Lock()
+	a.phase = phaseStopDone
+	started := make(map[auacornapi.Acorn]bool)
+	for _, instance := range a.started {
+		started[instance] = true
+	}
+	a.started = make([]auacornapi.Acorn, 0)
+	order := a.startOrder()
+	a.mu.Unlock()
+
+	began := a.notifyBefore(PhaseStop, "")
+	defer func() { a.notifyAfter(PhaseStop, "", began, err) }()
+
+	failures := make([]error, 0)
+	for i := len(order) - 1; i >= 0; i-- {
+		instance := order[i]
+		if _, ok := instance.(auacornapi.StoppableAcorn); !ok {
+			continue
+		}
+		if _, startable := instance.(auacornapi.StartableAcorn); startable && !started[instance] {
+			// never started, e.g. because Start() failed before it got to it
+			continue
+		}
+		if err := a.callAcorn(ctx, PhaseStop, instance); err != nil {
+			failures = append(failures, wrapLifecycleError(PhaseStop, instance, err))
+		}
+	}
+	return errors.Join(failures...)
+}
@@ -0,0 +1,142 @@
+package auacorn
+
+import (
+	"errors"
+	"github.com/StephanHCB/go-autumn-acorn-registry/test/mockc"
+	"github.com/StephanHCB/go-autumn-acorn-registry/test/rec"
+	"github.com/StephanHCB/go-autumn-acorn-registry/test/server"
+	"github.com/StephanHCB/go-autumn-acorn-registry/test/stopper"
+	"testing"
+)
+
+func TestStartStop_NormalLifecycle(t *testing.T) {
+	impl := New()
+	Registry = impl
+
+	Registry.Register(server.New(&server.ServerImpl{Name: "s1"}))
+	Registry.Register(server.New(&server.ServerImpl{Name: "s2"}))
+	Registry.Register(mockc.New)
+	Registry.Create()
+	// specifies: s1 is set up after s2
+	_ = Registry.AddSetupOrderRule(Registry.GetAcornByName("s2"), Registry.GetAcornByName("s1"))
+	_ = Registry.Assemble()
+
+	rec.Reset()
+	if Registry.Setup() != nil {
+		t.FailNow()
+	}
+	assertRecording(t, []string{"s2.SetupAcorn", "s1.SetupAcorn", "c.SetupAcorn"})
+
+	rec.Reset()
+	if impl.Start() != nil {
+		t.FailNow()
+	}
+	// in order of setup completion, mockc is not startable
+	assertRecording(t, []string{"s2.StartAcorn", "s1.StartAcorn"})
+
+	rec.Reset()
+	if impl.Stop() != nil {
+		t.FailNow()
+	}
+	assertRecording(t, []string{"s1.StopAcorn", "s2.StopAcorn"})
+
+	rec.Reset()
+	if Registry.Teardown() != nil {
+		t.FailNow()
+	}
+	assertRecording(t, []string{"c.TeardownAcorn", "s1.TeardownAcorn", "s2.TeardownAcorn"})
+}
+
+func TestStartStop_PhaseOrder(t *testing.T) {
+	impl := New()
+	Registry = impl
+
+	Registry.Register(server.New(&server.ServerImpl{Name: "s1"}))
+	Registry.Create()
+	_ = Registry.Assemble()
+
+	var phaseErr *PhaseOrderError
+	if err := impl.Start(); !errors.As(err, &phaseErr) || phaseErr.Method != "Start" {
+		t.FailNow()
+	}
+	_ = Registry.Setup()
+	if err := impl.Stop(); !errors.As(err, &phaseErr) || phaseErr.Method != "Stop" {
+		t.FailNow()
+	}
+	if impl.Start() != nil {
+		t.FailNow()
+	}
+	if err := impl.Start(); !errors.As(err, &phaseErr) || phaseErr.Method != "Start" {
+		t.FailNow()
+	}
+}
+
+func TestStartStop_TeardownStopsFirst(t *testing.T) {
+	impl := New()
+	Registry = impl
+
+	Registry.Register(server.New(&server.ServerImpl{Name: "s1"}))
+	Registry.Register(server.New(&server.ServerImpl{Name: "s2"}))
+	Registry.Create()
+	_ = Registry.Assemble()
+	_ = Registry.Setup()
+	_ = impl.Start()
+
+	rec.Reset()
+	if Registry.Teardown() != nil {
+		t.FailNow()
+	}
+	assertRecording(t, []string{"s2.StopAcorn", "s1.StopAcorn", "s2.TeardownAcorn", "s1.TeardownAcorn"})
+}
+
+func TestStartStop_StartFailure(t *testing.T) {
+	impl := New()
+	Registry = impl
+
+	Registry.Register(server.New(&server.ServerImpl{Name: "s1"}))
+	Registry.Register(server.New(&server.ServerImpl{Name: "s2", FailStart: true}))
+	Registry.Register(server.New(&server.ServerImpl{Name: "s3"}))
+	Registry.Create()
+	_ = Registry.Assemble()
+	_ = Registry.Setup()
+
+	rec.Reset()
+	err := impl.Start()
+	var lifecycleErr *LifecycleError
+	if !errors.As(err, &lifecycleErr) || lifecycleErr.Phase != PhaseStart || lifecycleErr.AcornName != "s2" {
+		t.FailNow()
+	}
+	// s3 is never started, and only s1 needs stopping
+	assertRecording(t, []string{"s1.StartAcorn", "s2.StartErr"})
+
+	rec.Reset()
+	if Registry.Teardown() != nil {
+		t.FailNow()
+	}
+	assertRecording(t, []string{"s1.StopAcorn", "s3.TeardownAcorn", "s2.TeardownAcorn", "s1.TeardownAcorn"})
+}
+
+func TestStartStop_StoppableOnly(t *testing.T) {
+	impl := New()
+	Registry = impl
+
+	Registry.Register(server.New(&server.ServerImpl{Name: "s1"}))
+	Registry.Register(stopper.New(&stopper.StopperImpl{Name: "st"}))
+	Registry.Register(server.New(&server.ServerImpl{Name: "s2"}))
+	Registry.Create()
+	_ = Registry.Assemble()
+	_ = Registry.Setup()
+
+	rec.Reset()
+	if impl.Start() != nil {
+		t.FailNow()
+	}
+	assertRecording(t, []string{"s1.StartAcorn", "s2.StartAcorn"})
+
+	rec.Reset()
+	if impl.Stop() != nil {
+		t.FailNow()
+	}
+	// st was never started, but it was set up, so it may be accepting work
+	assertRecording(t, []string{"s2.StopAcorn", "st.StopAcorn", "s1.StopAcorn"})
+}
@@ -0,0 +1,122 @@
+package auacorn
+
+import (
+	"fmt"
+)
+
+// WithStrictMode makes the registry check that each of its methods is called when its documentation allows it.
+//
+// Acorns may only call GetAcornByName, AddSetupOrderRule and AddTeardownOrderRule from AssembleAcorn,
+// SetupAfter only from SetupAcorn, and TeardownAfter only from TeardownAcorn. They may not call any of the
+// methods meant for the application, such as Register or Setup. The application in turn may not register
+// Acorns after Create(), look up Acorns before Create(), or use the testing methods at the wrong time.
+//
+// Each violation is reported as a ProtocolViolationError. Methods that return an error return it instead
+// of doing anything. The others still do their work, so strict mode can be switched on in tests without
+// changing the outcome. Either way, all violations are collected, see Violations().
+func WithStrictMode() Option {
+	return func(registry *AcornRegistryImpl) {
+		registry.strict = true
+	}
+}
+
+// ProtocolViolationError reports a registry method that was called at a time its documentation does not allow.
+//
+// Only reported in strict mode, see WithStrictMode().
+type ProtocolViolationError struct {
+	AcornName string // the Acorn that made the call, empty if it was not made by an Acorn
+	Method    string // name of the registry method that was called, e.g. "GetAcornByName"
+	When      string // when the call was made, e.g. "during setup" or "after Create()"
+}
+
+func (e *ProtocolViolationError) Error() string {
+	if e.AcornName == "" {
+		return fmt.Sprintf("%s called %s", e.Method, e.When)
+	}
+	return fmt.Sprintf("acorn %s called %s %s", e.AcornName, e.Method, e.When)
+}
+
+// Violations returns all protocol violations so far, in the order they occurred. Always empty unless
+// in strict mode, or with dependency guards, see WithDependencyGuards().
+func (a *AcornRegistryImpl) Violations() []error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	result := make([]error, len(a.violations))
+	copy(result, a.violations)
+	return result
+}
+
+// acornMethodPhases tells which methods Acorns may call, and from which of their lifecycle methods.
+var acornMethodPhases = map[string]Phase{
+	"GetAcornByName":       PhaseAssembly,
+	"AddSetupOrderRule":    PhaseAssembly,
+	"AddTeardownOrderRule": PhaseAssembly,
+	"SetupAfter":           PhaseSetup,
+	"SetupAfterCtx":        PhaseSetup,
+	"TeardownAfter":        PhaseTeardown,
+	"TeardownAfterCtx":     PhaseTeardown,
+}
+
+// violation checks in strict mode whether the Acorn of this scope may call method from its current
+// lifecycle method, and records the violation if not.
+func (s *acornScope) violation(method string) error {
+	if !s.strict {
+		return nil
+	}
+	if allowed, ok := acornMethodPhases[method]; ok && allowed == s.phase {
+		return nil
+	}
+	return s.recordViolation(&ProtocolViolationError{
+		AcornName: s.acorn.AcornName(),
+		Method:    method,
+		When:      "during " + string(s.phase),
+	})
+}
+
+// violation checks in strict mode whether method may be called in the current phase of the registry,
+// and records the violation if not.
+//
+// Only covers methods that do not check the phase anyway.
+func (a *AcornRegistryImpl) violation(method string) error {
+	if !a.strict {
+		return nil
+	}
+	a.mu.Lock()
+	when := ""
+	switch method {
+	case "Register", "RegisterOverride", "RegisterIf", "RegisterFor", "RegisterLazy", "RegisterGuardAdapter",
+		"ActivateProfiles", "Create":
+		if a.phase != 0 {
+			when = "after Create()"
+		}
+	case "CreateOverride", "SkipAssemble":
+		if a.phase == 0 {
+			when = "before Create()"
+		} else if a.phase != phaseCreateDone {
+			when = "after Assemble()"
+		}
+	case "SkipSetup":
+		if a.phase < phaseAssembleDone {
+			when = "before Assemble()"
+		} else if a.phase != phaseAssembleDone {
+			when = "after Setup()"
+		}
+	case "GetAcornByName", "SkipTeardown":
+		if a.phase == 0 {
+			when = "before Create()"
+		}
+	}
+	a.mu.Unlock()
+
+	if when == "" {
+		return nil
+	}
+	return a.recordViolation(&ProtocolViolationError{Method: method, When: when})
+}
+
+func (a *AcornRegistryImpl) recordViolation(violation *ProtocolViolationError) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.violations = append(a.violations, violation)
+	return violation
+}
@@ -0,0 +1,122 @@
+package auacorn
+
+import (
+	"errors"
+	auacornapi "github.com/StephanHCB/go-autumn-acorn-registry/api"
+	"github.com/StephanHCB/go-autumn-acorn-registry/test/hook"
+	"github.com/StephanHCB/go-autumn-acorn-registry/test/mockc"
+	"github.com/StephanHCB/go-autumn-acorn-registry/test/para"
+	"testing"
+)
+
+func assertViolations(t *testing.T, registry *AcornRegistryImpl, expected []string) {
+	actual := registry.Violations()
+	if len(actual) != len(expected) {
+		t.Errorf("unexpected number of violations: %v", actual)
+		return
+	}
+	for i := range expected {
+		if actual[i].Error() != expected[i] {
+			t.Errorf("unexpected violation at index %d: %s", i, actual[i].Error())
+		}
+	}
+}
+
+func TestStrictMode_AcornViolations(t *testing.T) {
+	impl := New(WithStrictMode())
+	Registry = impl
+
+	var assembleErr, setupErr error
+	Registry.Register(hook.New(&hook.HookImpl{
+		Name: "h",
+		OnAssemble: func(registry auacornapi.AcornRegistry) {
+			assembleErr = registry.SetupAfter(registry.GetAcornByName(mockc.MockCName))
+		},
+		OnSetup: func(registry auacornapi.AcornRegistry) {
+			_ = registry.GetAcornByName(mockc.MockCName)
+			setupErr = registry.Setup()
+		},
+	}))
+	Registry.Register(mockc.New)
+	Registry.Create()
+	if Registry.Assemble() != nil {
+		t.FailNow()
+	}
+	if Registry.Setup() != nil {
+		t.FailNow()
+	}
+
+	var violation *ProtocolViolationError
+	if !errors.As(assembleErr, &violation) || violation.AcornName != "h" || violation.Method != "SetupAfter" {
+		t.FailNow()
+	}
+	if !errors.As(setupErr, &violation) || violation.Method != "Setup" {
+		t.FailNow()
+	}
+	assertViolations(t, impl, []string{
+		"acorn h called SetupAfter during assembly",
+		"acorn h called GetAcornByName during setup",
+		"acorn h called Setup during setup",
+	})
+}
+
+func TestStrictMode_ApplicationViolations(t *testing.T) {
+	impl := New(WithStrictMode())
+	Registry = impl
+
+	if Registry.GetAcornByName("p1") != nil {
+		t.FailNow()
+	}
+	Registry.Register(para.New(&para.ParaImpl{Name: "p1"}))
+	if Registry.Create() != nil {
+		t.FailNow()
+	}
+	Registry.Register(para.New(&para.ParaImpl{Name: "p2"}))
+	if Registry.Assemble() != nil {
+		t.FailNow()
+	}
+	Registry.CreateOverride("p1", &para.ParaImpl{Name: "p1"})
+
+	var violation *ProtocolViolationError
+	if err := Registry.Create(); !errors.As(err, &violation) {
+		t.FailNow()
+	}
+	assertViolations(t, impl, []string{
+		"GetAcornByName called before Create()",
+		"Register called after Create()",
+		"CreateOverride called after Assemble()",
+		"Create called after Create()",
+	})
+}
+
+func TestStrictMode_NormalUse(t *testing.T) {
+	impl := New(WithStrictMode(), WithParallelSetup(0))
+	Registry = impl
+
+	Registry.Register(para.New(&para.ParaImpl{Name: "p1", SetupAfterNames: []string{"p2"}, TeardownAfterNames: []string{"p2"}, Spawn: true}))
+	Registry.Register(para.New(&para.ParaImpl{Name: "p2"}))
+	Registry.Create()
+	_ = Registry.AddSetupOrderRule(Registry.GetAcornByName("p2"), Registry.GetAcornByName("p1"))
+	if Registry.Assemble() != nil || Registry.Setup() != nil || Registry.Teardown() != nil {
+		t.FailNow()
+	}
+	assertViolations(t, impl, []string{})
+}
+
+func TestStrictMode_Off(t *testing.T) {
+	impl := New()
+	Registry = impl
+
+	Registry.Register(hook.New(&hook.HookImpl{
+		Name: "h",
+		OnSetup: func(registry auacornapi.AcornRegistry) {
+			_ = registry.GetAcornByName(mockc.MockCName)
+		},
+	}))
+	Registry.Register(mockc.New)
+	Registry.Create()
+	Registry.Register(mockc.New)
+	_ = Registry.Assemble()
+	_ = Registry.Setup()
+	assertViolations(t, impl, []string{})
+}
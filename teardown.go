@@ -0,0 +1,268 @@
+package auacorn
+
+import (
+	"context"
+	"errors"
+	auacornapi "github.com/StephanHCB/go-autumn-acorn-registry/api"
+)
+
+// SkipTeardown lets you mark an instance as already torn down, so it will be skipped during Teardown().
+//
+// useful for testing
+func (a *AcornRegistryImpl) SkipTeardown(instance auacornapi.Acorn) {
+	_ = a.violation("SkipTeardown")
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.phaseByInstance[instance] = phaseTeardownDone
+}
+
+// Teardown tears down all acorns that were set up, even if some of them fail.
+//
+// By default, acorns are torn down one by one in reverse order of setup completion, but TeardownAfter() and
+// teardown order rules can pull other acorns earlier. See WithParallelTeardown() for concurrent teardown.
+//
+// If the registry was started, but not stopped, it is stopped first.
+//
+// The returned error joins a LifecycleError or TimeoutError for each failed acorn, including those torn down
+// through TeardownAfter().
+func (a *AcornRegistryImpl) Teardown() error {
+	return a.TeardownCtx(context.Background())
+}
+
+// TeardownCtx is Teardown, but stops tearing down acorns once ctx is done. Each acorn that is left set up
+// is reported with a TimeoutError.
+func (a *AcornRegistryImpl) TeardownCtx(ctx context.Context) (err error) {
+	began := a.notifyBefore(PhaseTeardown, "")
+	defer func() { a.notifyAfter(PhaseTeardown, "", began, err) }()
+
+	ctx, cancel := a.withPhaseTimeout(ctx)
+	defer cancel()
+
+	// acorns must stop accepting work before anything is torn down
+	var stopErr error
+	a.mu.Lock()
+	running := a.phase == phaseStartDone
+	a.mu.Unlock()
+	if running {
+		stopErr = a.stop(ctx)
+	}
+
+	// we allow teardown even for lower phase numbers, so partial setup can be cleaned up
+	a.mu.Lock()
+	a.teardownRun = newPhaseRun(PhaseTeardown)
+	a.teardownFailures = make([]error, 0)
+	order := a.teardownOrder()
+	a.mu.Unlock()
+
+	if a.parallelTeardown {
+		_ = a.runConcurrently(a.teardownRun, a.teardownWorkers, false, a.claimReadyForTeardown, func(instance auacornapi.Acorn) error {
+			return a.runTeardown(ctx, instance)
+		})
+	} else {
+		for _, instance := range order {
+			a.teardownTopLevel(ctx, instance)
+		}
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.phase = phaseTeardownDone
+	return errors.Join(append([]error{stopErr}, a.teardownFailures...)...)
+}
+
+// teardownOrder is the reverse order of setup completion, followed by any instances that were set up
+// without the registry knowing when (e.g. due to SkipSetup), in iteration order.
+//
+// Instances may occur twice, so only tear down instances that are still set up. Caller must hold the lock.
+func (a *AcornRegistryImpl) teardownOrder() []auacornapi.Acorn {
+	result := make([]auacornapi.Acorn, 0, len(a.setupCompleted)+len(a.names))
+	for i := len(a.setupCompleted) - 1; i >= 0; i-- {
+		result = append(result, a.setupCompleted[i])
+	}
+	for _, name := range a.orderedNames() {
+		result = append(result, a.instancesByName[name])
+	}
+	return result
+}
+
+// teardownTopLevel tears down instance in the current goroutine, unless it is no longer set up.
+func (a *AcornRegistryImpl) teardownTopLevel(ctx context.Context, instance auacornapi.Acorn) {
+	a.mu.Lock()
+	if a.phaseByInstance[instance] != phaseSetupDone {
+		a.mu.Unlock()
+		return
+	}
+	a.claimForTeardown(instance)
+	a.mu.Unlock()
+
+	_ = a.runTeardown(ctx, instance)
+}
+
+// claimReadyForTeardown claims up to limit acorns in teardown order, whose teardown prerequisites are all
+// torn down, and which no other acorn that is still set up was set up after. A negative limit means no limit.
+//
+// If none is ready and none is in progress, the remaining dependencies cannot all be satisfied, so it claims
+// the first remaining acorn in teardown order anyway, letting TeardownAfter() sort out the rest.
+// Caller must hold the lock.
+func (a *AcornRegistryImpl) claimReadyForTeardown(limit int) []auacornapi.Acorn {
+	result := make([]auacornapi.Acorn, 0)
+	if limit == 0 {
+		return result
+	}
+	var first auacornapi.Acorn
+	inProgress := false
+	for _, instance := range a.teardownOrder() {
+		phase := a.phaseByInstance[instance]
+		if phase == phaseInRecursiveTeardown {
+			inProgress = true
+		}
+		if phase != phaseSetupDone {
+			continue
+		}
+		if first == nil {
+			first = instance
+		}
+		if a.teardownPrerequisitesDone(instance) {
+			a.claimForTeardown(instance)
+			result = append(result, instance)
+			if limit > 0 && len(result) >= limit {
+				break
+			}
+		}
+	}
+	if len(result) == 0 && !inProgress && first != nil {
+		a.claimForTeardown(first)
+		result = append(result, first)
+	}
+	return result
+}
+
+// teardownPrerequisitesDone tells whether everything that must be torn down before instance is torn down.
+// Caller must hold the lock.
+func (a *AcornRegistryImpl) teardownPrerequisitesDone(instance auacornapi.Acorn) bool {
+	stillUp := func(other auacornapi.Acorn) bool {
+		phase := a.phaseByInstance[other]
+		return phase == phaseSetupDone || phase == phaseInRecursiveTeardown
+	}
+	for _, prerequisite := range a.teardownBefore[instance] {
+		if stillUp(prerequisite.instance) {
+			return false
+		}
+	}
+	for _, dependent := range a.setupDependents[instance] {
+		if stillUp(dependent) {
+			return false
+		}
+	}
+	return true
+}
+
+// claimForTeardown marks an instance as being torn down, so nobody else starts tearing it down.
+// Caller must hold the lock.
+func (a *AcornRegistryImpl) claimForTeardown(instance auacornapi.Acorn) {
+	a.phaseByInstance[instance] = phaseInRecursiveTeardown
+	a.teardownRun.claim(instance)
+}
+
+// runTeardown tears down a claimed instance in the current goroutine, and lets everyone waiting for it know
+// the outcome.
+//
+// A failed teardown is recorded, and not retried.
+func (a *AcornRegistryImpl) runTeardown(ctx context.Context, instance auacornapi.Acorn) error {
+	err := a.injectExtraTeardownAfterCallsThenTeardown(ctx, instance)
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.phaseByInstance[instance] = phaseTeardownDone
+	if err != nil {
+		err = wrapLifecycleError(PhaseTeardown, instance, err)
+		a.teardownFailures = append(a.teardownFailures, err)
+	}
+	a.teardownRun.finish(instance, err)
+	return err
+}
+
+func (a *AcornRegistryImpl) injectExtraTeardownAfterCallsThenTeardown(ctx context.Context, instance auacornapi.Acorn) error {
+	a.mu.Lock()
+	extraPrerequisites := a.teardownBefore[instance]
+	a.mu.Unlock()
+	for _, prerequisite := range extraPrerequisites {
+		err := a.teardownAfter(ctx, instance, prerequisite.instance, prerequisite.via)
+		if err != nil {
+			return err
+		}
+	}
+	return a.callAcorn(ctx, PhaseTeardown, instance)
+}
+
+func (a *AcornRegistryImpl) TeardownAfter(otherAcorn auacornapi.Acorn) error {
+	return a.teardownAfter(context.Background(), nil, otherAcorn, EdgeTeardownAfter)
+}
+
+// TeardownAfterCtx is TeardownAfter, but gives up once ctx is done.
+func (a *AcornRegistryImpl) TeardownAfterCtx(ctx context.Context, otherAcorn auacornapi.Acorn) error {
+	return a.teardownAfter(ctx, nil, otherAcorn, EdgeTeardownAfter)
+}
+
+// teardownAfter makes sure otherAcorn is torn down before returning.
+//
+// Teardown is best-effort, so if otherAcorn fails, its failure is only recorded for Teardown(), and not returned
+// to requester, which can go on with its own teardown.
+//
+// If nobody is tearing it down yet, it is torn down in the current goroutine. If another goroutine is already
+// tearing it down, we wait for it to finish, unless requester is (directly or indirectly) what it is waiting for.
+//
+// requester is nil if not called on the registry passed to an acorn, which is rejected, as we could neither
+// detect cycles nor tell apart several goroutines waiting on behalf of the same acorn.
+func (a *AcornRegistryImpl) teardownAfter(ctx context.Context, requester auacornapi.Acorn, otherAcorn auacornapi.Acorn, via EdgeKind) error {
+	a.mu.Lock()
+	if a.teardownRun == nil {
+		a.mu.Unlock()
+		return &PhaseOrderError{Method: "TeardownAfter", Detail: "only allowed during teardown phase"}
+	}
+	if requester == nil {
+		a.mu.Unlock()
+		return &UnscopedCallError{Method: "TeardownAfter"}
+	}
+	run := a.teardownRun
+	otherAcorn = a.unguarded(otherAcorn)
+	a.observeEdge(requester.AcornName(), otherAcorn.AcornName(), via)
+
+	switch a.phaseByInstance[otherAcorn] {
+	case phaseSetupDone:
+		a.claimForTeardown(otherAcorn)
+		run.addWait(requester, otherAcorn, via)
+		a.mu.Unlock()
+
+		_ = a.runTeardown(ctx, otherAcorn)
+
+		a.mu.Lock()
+		run.removeWait(requester, otherAcorn)
+		a.mu.Unlock()
+		return nil
+	case phaseInRecursiveTeardown:
+		if cycle := run.findWaitCycle(requester, otherAcorn, via); cycle != nil {
+			a.mu.Unlock()
+			return cycle
+		}
+		run.addWait(requester, otherAcorn, via)
+		done := run.done[otherAcorn]
+		a.mu.Unlock()
+
+		var err error
+		select {
+		case <-done:
+		case <-ctx.Done():
+			err = &TimeoutError{Phase: PhaseTeardown, AcornName: otherAcorn.AcornName(), Err: ctx.Err()}
+		}
+
+		a.mu.Lock()
+		defer a.mu.Unlock()
+		run.removeWait(requester, otherAcorn)
+		return err
+	default:
+		// was already torn down, or never set up, that is ok
+		a.mu.Unlock()
+		return nil
+	}
+}
@@ -0,0 +1,181 @@
+package auacorn
+
+import (
+	"errors"
+	auacornapi "github.com/StephanHCB/go-autumn-acorn-registry/api"
+	"github.com/StephanHCB/go-autumn-acorn-registry/test/hook"
+	"github.com/StephanHCB/go-autumn-acorn-registry/test/para"
+	"github.com/StephanHCB/go-autumn-acorn-registry/test/rec"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestTeardown_Parallel_IndependentAcornsRunConcurrently(t *testing.T) {
+	Registry = New(WithParallelTeardown(0))
+
+	barrier := &sync.WaitGroup{}
+	barrier.Add(3)
+	Registry.Register(para.New(&para.ParaImpl{Name: "x", TeardownBarrier: barrier}))
+	Registry.Register(para.New(&para.ParaImpl{Name: "y", TeardownBarrier: barrier}))
+	Registry.Register(para.New(&para.ParaImpl{Name: "z", TeardownBarrier: barrier}))
+	Registry.Create()
+	_ = Registry.Assemble()
+	_ = Registry.Setup()
+
+	rec.Reset()
+	// would time out at the barrier if the acorns were torn down one by one
+	err := Registry.Teardown()
+	if err != nil {
+		t.Errorf("unexpected error: %s", err.Error())
+	}
+	if len(rec.Get()) != 6 {
+		t.Errorf("unexpected recording: %v", rec.Get())
+	}
+}
+
+func TestTeardown_Parallel_ReverseSetupDependencies(t *testing.T) {
+	Registry = New(WithParallelTeardown(0))
+
+	Registry.Register(para.New(&para.ParaImpl{Name: "x", SetupAfterNames: []string{"y"}, TeardownDelay: 20 * time.Millisecond}))
+	Registry.Register(para.New(&para.ParaImpl{Name: "y"}))
+	Registry.Create()
+	_ = Registry.Assemble()
+	_ = Registry.Setup()
+
+	rec.Reset()
+	err := Registry.Teardown()
+	if err != nil {
+		t.FailNow()
+	}
+	// x was set up after y, so y is not even started before x is done
+	assertRecording(t, []string{"x.PreTeardownAcorn", "x.TeardownAcorn", "y.PreTeardownAcorn", "y.TeardownAcorn"})
+}
+
+func TestTeardown_Parallel_DeclaredDependencies(t *testing.T) {
+	Registry = New(WithParallelTeardown(0))
+
+	Registry.Register(para.New(&para.ParaImpl{Name: "x", TeardownDeps: []string{"y"}}))
+	Registry.Register(para.New(&para.ParaImpl{Name: "y", TeardownDelay: 20 * time.Millisecond}))
+	Registry.Create()
+	_ = Registry.Assemble()
+	_ = Registry.Setup()
+
+	rec.Reset()
+	err := Registry.Teardown()
+	if err != nil {
+		t.FailNow()
+	}
+	assertRecording(t, []string{"y.PreTeardownAcorn", "y.TeardownAcorn", "x.PreTeardownAcorn", "x.TeardownAcorn"})
+}
+
+func TestTeardown_Parallel_TeardownAfterSameAcornOnlyOnce(t *testing.T) {
+	Registry = New(WithParallelTeardown(0))
+
+	Registry.Register(para.New(&para.ParaImpl{Name: "x", TeardownAfterNames: []string{"z"}}))
+	Registry.Register(para.New(&para.ParaImpl{Name: "y", TeardownAfterNames: []string{"z"}}))
+	Registry.Register(para.New(&para.ParaImpl{Name: "z", TeardownDelay: 50 * time.Millisecond}))
+	Registry.Create()
+	_ = Registry.Assemble()
+	_ = Registry.Setup()
+
+	rec.Reset()
+	err := Registry.Teardown()
+	if err != nil {
+		t.FailNow()
+	}
+	if countRecorded("z.PreTeardownAcorn") != 1 || countRecorded("z.TeardownAcorn") != 1 {
+		t.Errorf("z was not torn down exactly once: %v", rec.Get())
+	}
+	if indexRecorded("z.TeardownAcorn") > indexRecorded("x.TeardownAcorn") || indexRecorded("z.TeardownAcorn") > indexRecorded("y.TeardownAcorn") {
+		t.Errorf("TeardownAfter returned before z was torn down: %v", rec.Get())
+	}
+}
+
+func TestTeardown_Parallel_CycleAcrossGoroutines(t *testing.T) {
+	Registry = New(WithParallelTeardown(0))
+
+	Registry.Register(para.New(&para.ParaImpl{Name: "x", TeardownAfterNames: []string{"y"}}))
+	Registry.Register(para.New(&para.ParaImpl{Name: "y", TeardownAfterNames: []string{"x"}}))
+	Registry.Create()
+	_ = Registry.Assemble()
+	_ = Registry.Setup()
+
+	result := make(chan error)
+	go func() {
+		result <- Registry.Teardown()
+	}()
+	select {
+	case err := <-result:
+		var cycleErr *CycleError
+		if !errors.As(err, &cycleErr) || cycleErr.Phase != PhaseTeardown || len(cycleErr.Path) != 3 {
+			t.Errorf("expected cycle error, got: %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("deadlock in teardown")
+	}
+}
+
+func TestTeardown_GlobalRegistryFromSpawnedGoroutines(t *testing.T) {
+	Registry = New()
+
+	var scoped, global [2]error
+	Registry.Register(para.New(&para.ParaImpl{Name: "slow", TeardownDelay: 20 * time.Millisecond}))
+	Registry.Register(hook.New(&hook.HookImpl{Name: "x", OnTeardown: func(registry auacornapi.AcornRegistry) {
+		slow := registry.GetAcornByName("slow")
+		wg := &sync.WaitGroup{}
+		for i := range scoped {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				scoped[i] = registry.TeardownAfter(slow)
+				global[i] = Registry.TeardownAfter(slow)
+			}(i)
+		}
+		wg.Wait()
+	}}))
+	Registry.Create()
+	_ = Registry.Assemble()
+	_ = Registry.Setup()
+
+	if Registry.Teardown() != nil {
+		t.FailNow()
+	}
+	for i := range scoped {
+		var unscopedErr *UnscopedCallError
+		if scoped[i] != nil || !errors.As(global[i], &unscopedErr) {
+			t.Errorf("unexpected errors: %v, %v", scoped[i], global[i])
+		}
+	}
+}
+
+func TestTeardown_Parallel_CycleThroughGlobalRegistry(t *testing.T) {
+	Registry = New(WithParallelTeardown(0))
+
+	errs := make(chan error, 2)
+	Registry.Register(hook.New(&hook.HookImpl{Name: "h1", OnTeardown: func(_ auacornapi.AcornRegistry) {
+		errs <- Registry.TeardownAfter(Registry.GetAcornByName("h2"))
+	}}))
+	Registry.Register(hook.New(&hook.HookImpl{Name: "h2", OnTeardown: func(_ auacornapi.AcornRegistry) {
+		errs <- Registry.TeardownAfter(Registry.GetAcornByName("h1"))
+	}}))
+	Registry.Create()
+	_ = Registry.Assemble()
+	_ = Registry.Setup()
+
+	result := make(chan error)
+	go func() {
+		result <- Registry.Teardown()
+	}()
+	select {
+	case <-result:
+		for range []int{1, 2} {
+			var unscopedErr *UnscopedCallError
+			if err := <-errs; !errors.As(err, &unscopedErr) {
+				t.Errorf("expected unscoped call error, got: %v", err)
+			}
+		}
+	case <-time.After(time.Second):
+		t.Fatal("deadlock in teardown")
+	}
+}
@@ -0,0 +1,61 @@
+package ctxacorn
+
+import (
+	"context"
+	auacornapi "github.com/StephanHCB/go-autumn-acorn-registry/api"
+	"github.com/StephanHCB/go-autumn-acorn-registry/test/rec"
+	"time"
+)
+
+// context test, takes SetupDelay to set up unless its context is done first
+
+type CtxImpl struct {
+	Name       string
+	SetupDelay time.Duration
+}
+
+func New(impl *CtxImpl) auacornapi.Constructor {
+	return func() auacornapi.Acorn {
+		return impl
+	}
+}
+
+func (m *CtxImpl) AcornName() string {
+	return m.Name
+}
+
+func (m *CtxImpl) AssembleAcorn(_ auacornapi.AcornRegistry) error {
+	rec.Add(m.Name + ".AssembleAcorn")
+	return nil
+}
+
+func (m *CtxImpl) SetupAcorn(_ auacornapi.AcornRegistry) error {
+	rec.Add(m.Name + ".SetupAcorn")
+	return nil
+}
+
+func (m *CtxImpl) TeardownAcorn(_ auacornapi.AcornRegistry) error {
+	rec.Add(m.Name + ".TeardownAcorn")
+	return nil
+}
+
+func (m *CtxImpl) AssembleAcornCtx(_ context.Context, _ auacornapi.AcornRegistry) error {
+	rec.Add(m.Name + ".AssembleAcornCtx")
+	return nil
+}
+
+func (m *CtxImpl) SetupAcornCtx(ctx context.Context, _ auacornapi.AcornRegistry) error {
+	select {
+	case <-time.After(m.SetupDelay):
+		rec.Add(m.Name + ".SetupAcornCtx")
+		return nil
+	case <-ctx.Done():
+		rec.Add(m.Name + ".SetupCanceled")
+		return ctx.Err()
+	}
+}
+
+func (m *CtxImpl) TeardownAcornCtx(_ context.Context, _ auacornapi.AcornRegistry) error {
+	rec.Add(m.Name + ".TeardownAcornCtx")
+	return nil
+}
@@ -0,0 +1,46 @@
+package declareda
+
+import (
+	auacornapi "github.com/StephanHCB/go-autumn-acorn-registry/api"
+	"github.com/StephanHCB/go-autumn-acorn-registry/test/declaredb"
+	"github.com/StephanHCB/go-autumn-acorn-registry/test/rec"
+)
+
+// declared dependency test, sets up after b, but also tears down after b
+
+const DeclaredAName = "declareda"
+
+type DeclaredAImpl struct {
+}
+
+func New() auacornapi.Acorn {
+	rec.Add("a.New")
+	return &DeclaredAImpl{}
+}
+
+func (m *DeclaredAImpl) AcornName() string {
+	return DeclaredAName
+}
+
+func (m *DeclaredAImpl) SetupDependencies() []string {
+	return []string{declaredb.DeclaredBName}
+}
+
+func (m *DeclaredAImpl) TeardownDependencies() []string {
+	return []string{declaredb.DeclaredBName}
+}
+
+func (m *DeclaredAImpl) AssembleAcorn(_ auacornapi.AcornRegistry) error {
+	rec.Add("a.AssembleAcorn")
+	return nil
+}
+
+func (m *DeclaredAImpl) SetupAcorn(_ auacornapi.AcornRegistry) error {
+	rec.Add("a.SetupAcorn")
+	return nil
+}
+
+func (m *DeclaredAImpl) TeardownAcorn(_ auacornapi.AcornRegistry) error {
+	rec.Add("a.TeardownAcorn")
+	return nil
+}
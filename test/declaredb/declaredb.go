@@ -0,0 +1,47 @@
+package declaredb
+
+import (
+	auacornapi "github.com/StephanHCB/go-autumn-acorn-registry/api"
+	"github.com/StephanHCB/go-autumn-acorn-registry/test/rec"
+)
+
+// declared dependency test, declares no dependencies unless a test overrides the instance
+
+const DeclaredBName = "declaredb"
+
+type DeclaredBImpl struct {
+	SetupDeps    []string
+	TeardownDeps []string
+}
+
+func New() auacornapi.Acorn {
+	rec.Add("b.New")
+	return &DeclaredBImpl{}
+}
+
+func (m *DeclaredBImpl) AcornName() string {
+	return DeclaredBName
+}
+
+func (m *DeclaredBImpl) SetupDependencies() []string {
+	return m.SetupDeps
+}
+
+func (m *DeclaredBImpl) TeardownDependencies() []string {
+	return m.TeardownDeps
+}
+
+func (m *DeclaredBImpl) AssembleAcorn(_ auacornapi.AcornRegistry) error {
+	rec.Add("b.AssembleAcorn")
+	return nil
+}
+
+func (m *DeclaredBImpl) SetupAcorn(_ auacornapi.AcornRegistry) error {
+	rec.Add("b.SetupAcorn")
+	return nil
+}
+
+func (m *DeclaredBImpl) TeardownAcorn(_ auacornapi.AcornRegistry) error {
+	rec.Add("b.TeardownAcorn")
+	return nil
+}
@@ -0,0 +1,48 @@
+package declaredc
+
+import (
+	auacornapi "github.com/StephanHCB/go-autumn-acorn-registry/api"
+	"github.com/StephanHCB/go-autumn-acorn-registry/test/rec"
+)
+
+// declared dependency test, computes its dependencies using a function provided by the test
+
+const DeclaredCName = "declaredc"
+
+type DeclaredCImpl struct {
+	Declare func() []string // called for both setup and teardown dependencies
+}
+
+func New(impl *DeclaredCImpl) auacornapi.Constructor {
+	return func() auacornapi.Acorn {
+		rec.Add("c.New")
+		return impl
+	}
+}
+
+func (m *DeclaredCImpl) AcornName() string {
+	return DeclaredCName
+}
+
+func (m *DeclaredCImpl) SetupDependencies() []string {
+	return m.Declare()
+}
+
+func (m *DeclaredCImpl) TeardownDependencies() []string {
+	return m.Declare()
+}
+
+func (m *DeclaredCImpl) AssembleAcorn(_ auacornapi.AcornRegistry) error {
+	rec.Add("c.AssembleAcorn")
+	return nil
+}
+
+func (m *DeclaredCImpl) SetupAcorn(_ auacornapi.AcornRegistry) error {
+	rec.Add("c.SetupAcorn")
+	return nil
+}
+
+func (m *DeclaredCImpl) TeardownAcorn(_ auacornapi.AcornRegistry) error {
+	rec.Add("c.TeardownAcorn")
+	return nil
+}
@@ -0,0 +1,51 @@
+package faila
+
+import (
+	"errors"
+	auacornapi "github.com/StephanHCB/go-autumn-acorn-registry/api"
+	"github.com/StephanHCB/go-autumn-acorn-registry/test/mockc"
+	"github.com/StephanHCB/go-autumn-acorn-registry/test/rec"
+)
+
+// failure test, sets up after mockc, but then fails both setup and teardown
+
+const FailAName = "faila"
+
+var ErrSetup = errors.New("faila setup failed")
+
+var ErrTeardown = errors.New("faila teardown failed")
+
+type FailAImpl struct {
+	MockC mockc.MockC
+}
+
+func New() auacornapi.Acorn {
+	rec.Add("fa.New")
+	return &FailAImpl{}
+}
+
+func (m *FailAImpl) AcornName() string {
+	return FailAName
+}
+
+func (m *FailAImpl) AssembleAcorn(registry auacornapi.AcornRegistry) error {
+	rec.Add("fa.AssembleAcorn")
+	m.MockC = registry.GetAcornByName(mockc.MockCName).(mockc.MockC)
+
+	return nil
+}
+
+func (m *FailAImpl) SetupAcorn(registry auacornapi.AcornRegistry) error {
+	err := registry.SetupAfter(m.MockC.(auacornapi.Acorn))
+	if err != nil {
+		return err
+	}
+
+	rec.Add("fa.SetupErr")
+	return ErrSetup
+}
+
+func (m *FailAImpl) TeardownAcorn(_ auacornapi.AcornRegistry) error {
+	rec.Add("fa.TeardownErr")
+	return ErrTeardown
+}
@@ -0,0 +1,45 @@
+package hook
+
+import (
+	auacornapi "github.com/StephanHCB/go-autumn-acorn-registry/api"
+)
+
+// an acorn that runs arbitrary code in its lifecycle methods, to test how the registry reacts to misuse
+
+type HookImpl struct {
+	Name       string
+	OnAssemble func(registry auacornapi.AcornRegistry) // if set, called during assembly
+	OnSetup    func(registry auacornapi.AcornRegistry) // if set, called during setup
+	OnTeardown func(registry auacornapi.AcornRegistry) // if set, called during teardown
+}
+
+func New(impl *HookImpl) auacornapi.Constructor {
+	return func() auacornapi.Acorn {
+		return impl
+	}
+}
+
+func (h *HookImpl) AcornName() string {
+	return h.Name
+}
+
+func (h *HookImpl) AssembleAcorn(registry auacornapi.AcornRegistry) error {
+	if h.OnAssemble != nil {
+		h.OnAssemble(registry)
+	}
+	return nil
+}
+
+func (h *HookImpl) SetupAcorn(registry auacornapi.AcornRegistry) error {
+	if h.OnSetup != nil {
+		h.OnSetup(registry)
+	}
+	return nil
+}
+
+func (h *HookImpl) TeardownAcorn(registry auacornapi.AcornRegistry) error {
+	if h.OnTeardown != nil {
+		h.OnTeardown(registry)
+	}
+	return nil
+}
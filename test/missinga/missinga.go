@@ -0,0 +1,46 @@
+package missinga
+
+import (
+	auacornapi "github.com/StephanHCB/go-autumn-acorn-registry/api"
+	"github.com/StephanHCB/go-autumn-acorn-registry/test/mockc"
+	"github.com/StephanHCB/go-autumn-acorn-registry/test/rec"
+)
+
+// missing dependency test, looks up an unknown acorn using auacornapi.Get
+
+const MissingAName = "missinga"
+
+type MissingAImpl struct {
+	MockC mockc.MockC
+}
+
+func New() auacornapi.Acorn {
+	rec.Add("a.New")
+	return &MissingAImpl{}
+}
+
+func (m *MissingAImpl) AcornName() string {
+	return MissingAName
+}
+
+func (m *MissingAImpl) AssembleAcorn(registry auacornapi.AcornRegistry) error {
+	rec.Add("a.AssembleAcorn")
+	var err error
+	m.MockC, err = auacornapi.Get[mockc.MockC](registry, m, "unknownx")
+	if err != nil {
+		rec.Add("a.AssembleErr")
+		return err
+	}
+
+	return nil
+}
+
+func (m *MissingAImpl) SetupAcorn(_ auacornapi.AcornRegistry) error {
+	rec.Add("a.SetupAcorn")
+	return nil
+}
+
+func (m *MissingAImpl) TeardownAcorn(_ auacornapi.AcornRegistry) error {
+	rec.Add("a.TeardownAcorn")
+	return nil
+}
@@ -0,0 +1,41 @@
+package missingb
+
+import (
+	auacornapi "github.com/StephanHCB/go-autumn-acorn-registry/api"
+	"github.com/StephanHCB/go-autumn-acorn-registry/test/mockc"
+	"github.com/StephanHCB/go-autumn-acorn-registry/test/rec"
+)
+
+// missing dependency test, looks up an unknown acorn and type-casts it directly, which panics
+
+const MissingBName = "missingb"
+
+type MissingBImpl struct {
+	MockC mockc.MockC
+}
+
+func New() auacornapi.Acorn {
+	rec.Add("b.New")
+	return &MissingBImpl{}
+}
+
+func (m *MissingBImpl) AcornName() string {
+	return MissingBName
+}
+
+func (m *MissingBImpl) AssembleAcorn(registry auacornapi.AcornRegistry) error {
+	rec.Add("b.AssembleAcorn")
+	m.MockC = registry.GetAcornByName("unknowny").(mockc.MockC)
+
+	return nil
+}
+
+func (m *MissingBImpl) SetupAcorn(_ auacornapi.AcornRegistry) error {
+	rec.Add("b.SetupAcorn")
+	return nil
+}
+
+func (m *MissingBImpl) TeardownAcorn(_ auacornapi.AcornRegistry) error {
+	rec.Add("b.TeardownAcorn")
+	return nil
+}
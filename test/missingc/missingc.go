@@ -0,0 +1,42 @@
+package missingc
+
+import (
+	auacornapi "github.com/StephanHCB/go-autumn-acorn-registry/api"
+	"github.com/StephanHCB/go-autumn-acorn-registry/test/mockc"
+	"github.com/StephanHCB/go-autumn-acorn-registry/test/rec"
+)
+
+// missing dependency test, looks up an unknown acorn, but then panics for an unrelated reason
+
+const MissingCName = "missingc"
+
+type MissingCImpl struct {
+	MockC mockc.MockC
+}
+
+func New() auacornapi.Acorn {
+	rec.Add("c.New")
+	return &MissingCImpl{}
+}
+
+func (m *MissingCImpl) AcornName() string {
+	return MissingCName
+}
+
+func (m *MissingCImpl) AssembleAcorn(registry auacornapi.AcornRegistry) error {
+	rec.Add("c.AssembleAcorn")
+	if instance := registry.GetAcornByName("unknownz"); instance != nil {
+		m.MockC = instance.(mockc.MockC)
+	}
+	panic("unrelated failure")
+}
+
+func (m *MissingCImpl) SetupAcorn(_ auacornapi.AcornRegistry) error {
+	rec.Add("c.SetupAcorn")
+	return nil
+}
+
+func (m *MissingCImpl) TeardownAcorn(_ auacornapi.AcornRegistry) error {
+	rec.Add("c.TeardownAcorn")
+	return nil
+}
@@ -31,8 +31,15 @@ func (m *MockAImpl) AcornName() string {
 
 func (m *MockAImpl) AssembleAcorn(registry auacornapi.AcornRegistry) error {
 	rec.Add("a.AssembleAcorn")
-	m.MockB = registry.GetAcornByName(mockb.MockBName).(mockb.MockB)
-	m.MockC = registry.GetAcornByName(mockc.MockCName).(mockc.MockC)
+	var err error
+	m.MockB, err = auacornapi.Get[mockb.MockB](registry, m, mockb.MockBName)
+	if err != nil {
+		return err
+	}
+	m.MockC, err = auacornapi.Get[mockc.MockC](registry, m, mockc.MockCName)
+	if err != nil {
+		return err
+	}
 
 	return nil
 }
@@ -0,0 +1,20 @@
+package mockc
+
+import (
+	auacornapi "github.com/StephanHCB/go-autumn-acorn-registry/api"
+)
+
+// guard proxy, as a library author would provide it
+
+type guardedMockC struct {
+	auacornapi.AcornGuard
+}
+
+func GuardAdapter(guard auacornapi.AcornGuard) auacornapi.Acorn {
+	return &guardedMockC{AcornGuard: guard}
+}
+
+func (g *guardedMockC) IsC() bool {
+	g.Check("IsC")
+	return g.Target().(MockC).IsC()
+}
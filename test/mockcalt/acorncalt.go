@@ -0,0 +1,42 @@
+package mockcalt
+
+import (
+	auacornapi "github.com/StephanHCB/go-autumn-acorn-registry/api"
+	"github.com/StephanHCB/go-autumn-acorn-registry/test/mockc"
+	"github.com/StephanHCB/go-autumn-acorn-registry/test/rec"
+)
+
+// alternative implementation of mockc.MockC under the same name, for duplicate and override tests
+
+type MockCAltImpl struct {
+}
+
+func New() auacornapi.Acorn {
+	rec.Add("calt.New")
+	return &MockCAltImpl{}
+}
+
+func (m *MockCAltImpl) AcornName() string {
+	return mockc.MockCName
+}
+
+func (m *MockCAltImpl) AssembleAcorn(_ auacornapi.AcornRegistry) error {
+	rec.Add("calt.AssembleAcorn")
+	return nil
+}
+
+func (m *MockCAltImpl) SetupAcorn(_ auacornapi.AcornRegistry) error {
+	rec.Add("calt.SetupAcorn")
+	return nil
+}
+
+func (m *MockCAltImpl) TeardownAcorn(_ auacornapi.AcornRegistry) error {
+	rec.Add("calt.TeardownAcorn")
+	return nil
+}
+
+// MockC
+
+func (m *MockCAltImpl) IsC() bool {
+	return true
+}
@@ -0,0 +1,143 @@
+package para
+
+import (
+	"errors"
+	auacornapi "github.com/StephanHCB/go-autumn-acorn-registry/api"
+	"github.com/StephanHCB/go-autumn-acorn-registry/test/rec"
+	"sync"
+	"time"
+)
+
+// configurable acorn shared by the tests, so one package can play all the acorns of a scenario,
+// including acorns that wait for each other during setup and teardown
+
+var ErrBarrierTimeout = errors.New("timed out waiting for other acorns at barrier")
+
+type ParaImpl struct {
+	Name            string
+	SetupAfterNames []string        // looked up during assembly, then SetupAfter() during setup
+	SetupDeps       []string        // declared setup dependencies
+	Delay           time.Duration   // sleep during setup
+	Barrier         *sync.WaitGroup // if set, setup only completes once all acorns sharing it have arrived
+	Spawn           bool            // if set, calls SetupAfter() for each of SetupAfterNames from its own goroutine
+	OnSetup         func()          // if set, called at the end of setup
+
+	TeardownAfterNames []string        // looked up during assembly, then TeardownAfter() during teardown
+	TeardownDeps       []string        // declared teardown dependencies
+	TeardownDelay      time.Duration   // sleep during teardown
+	TeardownBarrier    *sync.WaitGroup // if set, teardown only completes once all acorns sharing it have arrived
+	TeardownBlock      chan struct{}   // if set, teardown only completes once it is closed
+
+	setupAfter    []auacornapi.Acorn
+	teardownAfter []auacornapi.Acorn
+}
+
+func New(impl *ParaImpl) auacornapi.Constructor {
+	return func() auacornapi.Acorn {
+		return impl
+	}
+}
+
+func (m *ParaImpl) AcornName() string {
+	return m.Name
+}
+
+func (m *ParaImpl) SetupDependencies() []string {
+	return m.SetupDeps
+}
+
+func (m *ParaImpl) TeardownDependencies() []string {
+	return m.TeardownDeps
+}
+
+func (m *ParaImpl) AssembleAcorn(registry auacornapi.AcornRegistry) error {
+	for _, name := range m.SetupAfterNames {
+		m.setupAfter = append(m.setupAfter, registry.GetAcornByName(name))
+	}
+	for _, name := range m.TeardownAfterNames {
+		m.teardownAfter = append(m.teardownAfter, registry.GetAcornByName(name))
+	}
+	return nil
+}
+
+func (m *ParaImpl) SetupAcorn(registry auacornapi.AcornRegistry) error {
+	rec.Add(m.Name + ".PreSetupAcorn")
+	if m.Spawn {
+		if err := m.setupAfterSpawned(registry); err != nil {
+			rec.Add(m.Name + ".SetupErr")
+			return err
+		}
+	}
+	for _, other := range m.setupAfter {
+		if err := registry.SetupAfter(other); err != nil {
+			rec.Add(m.Name + ".SetupErr")
+			return err
+		}
+	}
+
+	if err := meet(m.Barrier); err != nil {
+		return err
+	}
+	time.Sleep(m.Delay)
+
+	rec.Add(m.Name + ".SetupAcorn")
+	if m.OnSetup != nil {
+		m.OnSetup()
+	}
+	return nil
+}
+
+func (m *ParaImpl) TeardownAcorn(registry auacornapi.AcornRegistry) error {
+	rec.Add(m.Name + ".PreTeardownAcorn")
+	for _, other := range m.teardownAfter {
+		if err := registry.TeardownAfter(other); err != nil {
+			rec.Add(m.Name + ".TeardownErr")
+			return err
+		}
+	}
+
+	if err := meet(m.TeardownBarrier); err != nil {
+		return err
+	}
+	time.Sleep(m.TeardownDelay)
+	if m.TeardownBlock != nil {
+		<-m.TeardownBlock
+	}
+
+	rec.Add(m.Name + ".TeardownAcorn")
+	return nil
+}
+
+func (m *ParaImpl) setupAfterSpawned(registry auacornapi.AcornRegistry) error {
+	errs := make(chan error, len(m.setupAfter))
+	for _, other := range m.setupAfter {
+		go func(other auacornapi.Acorn) {
+			errs <- registry.SetupAfter(other)
+		}(other)
+	}
+	var result error
+	for range m.setupAfter {
+		if err := <-errs; err != nil {
+			result = err
+		}
+	}
+	return result
+}
+
+func meet(barrier *sync.WaitGroup) error {
+	if barrier == nil {
+		return nil
+	}
+	barrier.Done()
+	arrived := make(chan struct{})
+	go func() {
+		barrier.Wait()
+		close(arrived)
+	}()
+	select {
+	case <-arrived:
+		return nil
+	case <-time.After(time.Second):
+		return ErrBarrierTimeout
+	}
+}
@@ -1,15 +1,27 @@
 package rec
 
+import "sync"
+
+var mutex sync.Mutex
+
 var recording = make([]string, 0)
 
 func Reset() {
+	mutex.Lock()
+	defer mutex.Unlock()
 	recording = make([]string, 0)
 }
 
 func Add(entry string) {
+	mutex.Lock()
+	defer mutex.Unlock()
 	recording = append(recording, entry)
 }
 
 func Get() []string {
-	return recording
+	mutex.Lock()
+	defer mutex.Unlock()
+	result := make([]string, len(recording))
+	copy(result, recording)
+	return result
 }
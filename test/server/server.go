@@ -0,0 +1,54 @@
+package server
+
+import (
+	"errors"
+	auacornapi "github.com/StephanHCB/go-autumn-acorn-registry/api"
+	"github.com/StephanHCB/go-autumn-acorn-registry/test/rec"
+)
+
+// start/stop test, a server that is started after setup, and optionally fails to start
+
+var ErrStart = errors.New("server start failed")
+
+type ServerImpl struct {
+	Name      string
+	FailStart bool
+}
+
+func New(impl *ServerImpl) auacornapi.Constructor {
+	return func() auacornapi.Acorn {
+		return impl
+	}
+}
+
+func (m *ServerImpl) AcornName() string {
+	return m.Name
+}
+
+func (m *ServerImpl) AssembleAcorn(_ auacornapi.AcornRegistry) error {
+	return nil
+}
+
+func (m *ServerImpl) SetupAcorn(_ auacornapi.AcornRegistry) error {
+	rec.Add(m.Name + ".SetupAcorn")
+	return nil
+}
+
+func (m *ServerImpl) StartAcorn(_ auacornapi.AcornRegistry) error {
+	if m.FailStart {
+		rec.Add(m.Name + ".StartErr")
+		return ErrStart
+	}
+	rec.Add(m.Name + ".StartAcorn")
+	return nil
+}
+
+func (m *ServerImpl) StopAcorn(_ auacornapi.AcornRegistry) error {
+	rec.Add(m.Name + ".StopAcorn")
+	return nil
+}
+
+func (m *ServerImpl) TeardownAcorn(_ auacornapi.AcornRegistry) error {
+	rec.Add(m.Name + ".TeardownAcorn")
+	return nil
+}
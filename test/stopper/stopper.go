@@ -0,0 +1,41 @@
+package stopper
+
+import (
+	auacornapi "github.com/StephanHCB/go-autumn-acorn-registry/api"
+	"github.com/StephanHCB/go-autumn-acorn-registry/test/rec"
+)
+
+// start/stop test, accepts work as soon as it is set up, so it can be stopped, but not started
+
+type StopperImpl struct {
+	Name string
+}
+
+func New(impl *StopperImpl) auacornapi.Constructor {
+	return func() auacornapi.Acorn {
+		return impl
+	}
+}
+
+func (m *StopperImpl) AcornName() string {
+	return m.Name
+}
+
+func (m *StopperImpl) AssembleAcorn(_ auacornapi.AcornRegistry) error {
+	return nil
+}
+
+func (m *StopperImpl) SetupAcorn(_ auacornapi.AcornRegistry) error {
+	rec.Add(m.Name + ".SetupAcorn")
+	return nil
+}
+
+func (m *StopperImpl) StopAcorn(_ auacornapi.AcornRegistry) error {
+	rec.Add(m.Name + ".StopAcorn")
+	return nil
+}
+
+func (m *StopperImpl) TeardownAcorn(_ auacornapi.AcornRegistry) error {
+	rec.Add(m.Name + ".TeardownAcorn")
+	return nil
+}
@@ -0,0 +1,160 @@
+package auacorn
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+	"text/tabwriter"
+	"time"
+)
+
+// PhaseTiming is how long one lifecycle method of an Acorn took.
+type PhaseTiming struct {
+	// Inclusive is the wall time of the call, including everything the Acorn waited for in SetupAfter()
+	// or TeardownAfter().
+	Inclusive time.Duration
+
+	// Exclusive is Inclusive without the time spent in SetupAfter() or TeardownAfter(), that is,
+	// the time the Acorn itself is responsible for.
+	Exclusive time.Duration
+}
+
+// AcornTiming is how long the AssembleAcorn, SetupAcorn and TeardownAcorn calls of an Acorn took.
+//
+// A phase the Acorn has not completed yet is zero.
+type AcornTiming struct {
+	AcornName string
+	Assembly  PhaseTiming
+	Setup     PhaseTiming
+	Teardown  PhaseTiming
+}
+
+// Exclusive is the total of the exclusive times of all phases.
+func (t AcornTiming) Exclusive() time.Duration {
+	return t.Assembly.Exclusive + t.Setup.Exclusive + t.Teardown.Exclusive
+}
+
+// Inclusive is the total of the inclusive times of all phases.
+func (t AcornTiming) Inclusive() time.Duration {
+	return t.Assembly.Inclusive + t.Setup.Inclusive + t.Teardown.Inclusive
+}
+
+// TimingReport lists the timings of all Acorns, most expensive first.
+type TimingReport []AcornTiming
+
+// TimingReport measures how long each Acorn took so far, sorted by exclusive total, most expensive first.
+//
+// Acorns whose lifecycle methods were never called are left out.
+func (a *AcornRegistryImpl) TimingReport() TimingReport {
+	a.mu.Lock()
+	report := make(TimingReport, 0, len(a.timings))
+	for _, timing := range a.timings {
+		report = append(report, *timing)
+	}
+	a.mu.Unlock()
+
+	sort.Slice(report, func(i, j int) bool {
+		if report[i].Exclusive() != report[j].Exclusive() {
+			return report[i].Exclusive() > report[j].Exclusive()
+		}
+		return report[i].AcornName < report[j].AcornName
+	})
+	return report
+}
+
+// WriteTable writes the report as a human-readable table, one Acorn per line.
+//
+// Inclusive times are only shown where they differ from exclusive times, that is, for setup and teardown.
+func (r TimingReport) WriteTable(w io.Writer) error {
+	table := tabwriter.NewWriter(w, 0, 0, 2, ' ', tabwriter.AlignRight)
+	_, _ = fmt.Fprintln(table, "ACORN\tTOTAL\tTOTAL INCL.\tASSEMBLY\tSETUP\tSETUP INCL.\tTEARDOWN\tTEARDOWN INCL.\t")
+	for _, timing := range r {
+		_, _ = fmt.Fprintf(table, "%s\t%v\t%v\t%v\t%v\t%v\t%v\t%v\t\n",
+			timing.AcornName,
+			roundTiming(timing.Exclusive()),
+			roundTiming(timing.Inclusive()),
+			roundTiming(timing.Assembly.Exclusive),
+			roundTiming(timing.Setup.Exclusive),
+			roundTiming(timing.Setup.Inclusive),
+			roundTiming(timing.Teardown.Exclusive),
+			roundTiming(timing.Teardown.Inclusive),
+		)
+	}
+	return table.Flush()
+}
+
+func (r TimingReport) String() string {
+	var builder strings.Builder
+	_ = r.WriteTable(&builder)
+	return builder.String()
+}
+
+func roundTiming(duration time.Duration) time.Duration {
+	return duration.Round(time.Microsecond)
+}
+
+// recordTiming stores how long the call of an acorn's lifecycle method for phase took. Start and stop are not
+// part of the report.
+func (a *AcornRegistryImpl) recordTiming(phase Phase, acornName string, inclusive time.Duration, nested time.Duration) {
+	exclusive := inclusive - nested
+	if exclusive < 0 {
+		exclusive = 0
+	}
+	measured := PhaseTiming{Inclusive: inclusive, Exclusive: exclusive}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	timing, ok := a.timings[acornName]
+	if !ok {
+		timing = &AcornTiming{AcornName: acornName}
+		a.timings[acornName] = timing
+	}
+	switch phase {
+	case PhaseAssembly:
+		timing.Assembly = measured
+	case PhaseSetup:
+		timing.Setup = measured
+	case PhaseTeardown:
+		timing.Teardown = measured
+	}
+}
+
+// nestedClock measures how much of an acorn's lifecycle method call was spent in SetupAfter or TeardownAfter.
+//
+// Acorns may call those from several goroutines at once, so overlapping calls are only counted once.
+type nestedClock struct {
+	mu     sync.Mutex
+	active int
+	since  time.Time
+	total  time.Duration
+}
+
+func (c *nestedClock) enter() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.active == 0 {
+		c.since = time.Now()
+	}
+	c.active++
+}
+
+func (c *nestedClock) leave() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.active--
+	if c.active == 0 {
+		c.total += time.Since(c.since)
+	}
+}
+
+// elapsed is the time spent in nested calls so far, including calls still running.
+func (c *nestedClock) elapsed() time.Duration {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.active > 0 {
+		return c.total + time.Since(c.since)
+	}
+	return c.total
+}
@@ -0,0 +1,64 @@
+package auacorn
+
+import (
+	"github.com/StephanHCB/go-autumn-acorn-registry/test/para"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTiming_ExclusiveOfSetupAfter(t *testing.T) {
+	impl := New()
+	Registry = impl
+
+	Registry.Register(para.New(&para.ParaImpl{Name: "p1", SetupAfterNames: []string{"p2"}, Delay: 10 * time.Millisecond}))
+	Registry.Register(para.New(&para.ParaImpl{Name: "p2", Delay: 50 * time.Millisecond}))
+	Registry.Register(para.New(&para.ParaImpl{Name: "p3"}))
+	Registry.Create()
+	_ = Registry.Assemble()
+	if Registry.Setup() != nil {
+		t.FailNow()
+	}
+
+	report := impl.TimingReport()
+	if len(report) != 3 {
+		t.FailNow()
+	}
+	// p1 took longest including p2, but p2 is the expensive one
+	if report[0].AcornName != "p2" || report[1].AcornName != "p1" || report[2].AcornName != "p3" {
+		t.Errorf("unexpected order: %s, %s, %s", report[0].AcornName, report[1].AcornName, report[2].AcornName)
+	}
+	p2, p1 := report[0], report[1]
+	if p2.Setup.Exclusive < 50*time.Millisecond || p2.Setup.Inclusive != p2.Setup.Exclusive {
+		t.Errorf("unexpected timing of p2: %+v", p2.Setup)
+	}
+	if p1.Setup.Inclusive < 60*time.Millisecond || p1.Setup.Exclusive < 10*time.Millisecond || p1.Setup.Exclusive >= 50*time.Millisecond {
+		t.Errorf("unexpected timing of p1: %+v", p1.Setup)
+	}
+	if p1.Inclusive() != p1.Assembly.Inclusive+p1.Setup.Inclusive || p1.Teardown != (PhaseTiming{}) {
+		t.Errorf("unexpected totals of p1: %+v", p1)
+	}
+}
+
+func TestTiming_Table(t *testing.T) {
+	impl := New()
+	Registry = impl
+
+	Registry.Register(para.New(&para.ParaImpl{Name: "fast"}))
+	Registry.Register(para.New(&para.ParaImpl{Name: "slow", Delay: 20 * time.Millisecond}))
+	Registry.Create()
+	_ = Registry.Assemble()
+	_ = Registry.Setup()
+	_ = Registry.Teardown()
+
+	lines := strings.Split(strings.TrimRight(impl.TimingReport().String(), "\n"), "\n")
+	if len(lines) != 3 {
+		t.FailNow()
+	}
+	if !strings.Contains(lines[0], "ACORN") || !strings.Contains(lines[0], "SETUP INCL.") {
+		t.Errorf("unexpected header: %s", lines[0])
+	}
+	if !strings.HasPrefix(strings.TrimSpace(lines[1]), "slow") || !strings.HasPrefix(strings.TrimSpace(lines[2]), "fast") {
+		t.Errorf("unexpected table:\n%s", strings.Join(lines, "\n"))
+	}
+}
@@ -0,0 +1,100 @@
+package auacorn
+
+import (
+	"errors"
+	auacornapi "github.com/StephanHCB/go-autumn-acorn-registry/api"
+	"sort"
+)
+
+// Validate checks the dependencies known after Assemble() for wiring mistakes, without setting up any Acorn.
+// It may also be called after Assemble() failed, to get a more complete picture than the error of Assemble().
+//
+// It looks at order rules, declared dependencies and lookups made during assembly, and reports
+//   - a CycleError for the first circular setup dependency, and the first circular teardown dependency,
+//   - a MissingAcornError for each reference to a name no Acorn has,
+//   - an OverriddenAcornError for each dependency on an instance that CreateOverride() has since replaced,
+//     which would silently be ignored during Setup() and Teardown().
+//
+// Dependencies only expressed by calling SetupAfter() or TeardownAfter() cannot be known before running them.
+// The returned error joins all problems found, and is nil if there are none.
+//
+// useful in a unit test of your application's wiring
+func (a *AcornRegistryImpl) Validate() error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if !a.assembled {
+		return &PhaseOrderError{Method: "Validate", Detail: "must come after Assemble()"}
+	}
+
+	problems := make([]error, 0)
+	if err := a.findCycle(PhaseSetup, a.setupBefore); err != nil {
+		problems = append(problems, err)
+	}
+	if err := a.findCycle(PhaseTeardown, a.teardownBefore); err != nil {
+		problems = append(problems, err)
+	}
+	problems = append(problems, a.danglingReferences()...)
+	problems = append(problems, a.overriddenReferences(a.setupBefore)...)
+	problems = append(problems, a.overriddenReferences(a.teardownBefore)...)
+	return errors.Join(problems...)
+}
+
+// danglingReferences reports each observed reference to an unknown name once. Caller must hold the lock.
+func (a *AcornRegistryImpl) danglingReferences() []error {
+	result := make([]error, 0)
+	seen := make(map[MissingAcornError]bool)
+	for _, observed := range a.observedEdges {
+		if _, ok := a.instancesByName[observed.To]; ok {
+			continue
+		}
+		missing := MissingAcornError{Requester: observed.From, AcornName: observed.To}
+		if !seen[missing] {
+			seen[missing] = true
+			result = append(result, &missing)
+		}
+	}
+	return result
+}
+
+// overriddenReferences reports each dependency in before whose dependent or prerequisite is no longer
+// the current instance for its name. Caller must hold the lock.
+func (a *AcornRegistryImpl) overriddenReferences(before map[auacornapi.Acorn][]edge) []error {
+	result := make([]error, 0)
+	replaced := func(instance auacornapi.Acorn) bool {
+		return a.instancesByName[instance.AcornName()] != instance
+	}
+	// iterate in a stable order, replaced dependents come last as they are not among the current instances
+	dependents := make([]auacornapi.Acorn, 0, len(before))
+	for _, name := range a.orderedNames() {
+		if _, ok := before[a.instancesByName[name]]; ok {
+			dependents = append(dependents, a.instancesByName[name])
+		}
+	}
+	replacedDependents := make([]auacornapi.Acorn, 0)
+	for dependent := range before {
+		if replaced(dependent) {
+			replacedDependents = append(replacedDependents, dependent)
+		}
+	}
+	sort.SliceStable(replacedDependents, func(i, j int) bool {
+		return replacedDependents[i].AcornName() < replacedDependents[j].AcornName()
+	})
+	dependents = append(dependents, replacedDependents...)
+
+	for _, dependent := range dependents {
+		for _, prerequisite := range before[dependent] {
+			for _, instance := range []auacornapi.Acorn{dependent, prerequisite.instance} {
+				if replaced(instance) {
+					result = append(result, &OverriddenAcornError{
+						AcornName: instance.AcornName(),
+						From:      dependent.AcornName(),
+						To:        prerequisite.instance.AcornName(),
+						Via:       prerequisite.via,
+					})
+					break
+				}
+			}
+		}
+	}
+	return result
+}
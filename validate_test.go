@@ -0,0 +1,108 @@
+package auacorn
+
+import (
+	"errors"
+	"github.com/StephanHCB/go-autumn-acorn-registry/test/para"
+	"github.com/StephanHCB/go-autumn-acorn-registry/test/rec"
+	"testing"
+)
+
+func TestValidate_Valid(t *testing.T) {
+	impl := New()
+	Registry = impl
+
+	Registry.Register(para.New(&para.ParaImpl{Name: "p1", SetupAfterNames: []string{"p2"}, TeardownDeps: []string{"p2"}}))
+	Registry.Register(para.New(&para.ParaImpl{Name: "p2", SetupDeps: []string{"p3"}}))
+	Registry.Register(para.New(&para.ParaImpl{Name: "p3"}))
+	Registry.Create()
+
+	var phaseErr *PhaseOrderError
+	if err := impl.Validate(); !errors.As(err, &phaseErr) || phaseErr.Method != "Validate" {
+		t.FailNow()
+	}
+
+	if Registry.Assemble() != nil {
+		t.FailNow()
+	}
+	rec.Reset()
+	if err := impl.Validate(); err != nil {
+		t.Errorf("unexpected error: %s", err.Error())
+	}
+	// nothing was set up
+	assertRecording(t, []string{})
+}
+
+func TestValidate_AfterFailedAssemble(t *testing.T) {
+	impl := New()
+	Registry = impl
+
+	Registry.Register(para.New(&para.ParaImpl{Name: "p1", SetupAfterNames: []string{"ghost"}}))
+	Registry.Register(para.New(&para.ParaImpl{Name: "p2"}))
+	Registry.Register(para.New(&para.ParaImpl{Name: "p3"}))
+	Registry.Create()
+	_ = Registry.AddSetupOrderRule(Registry.GetAcornByName("p2"), Registry.GetAcornByName("p3"))
+	_ = Registry.AddSetupOrderRule(Registry.GetAcornByName("p3"), Registry.GetAcornByName("p2"))
+	if Registry.Assemble() == nil {
+		t.FailNow()
+	}
+
+	err := impl.Validate()
+	var cycleErr *CycleError
+	if !errors.As(err, &cycleErr) || cycleErr.Phase != PhaseSetup {
+		t.FailNow()
+	}
+	var missingErr *MissingAcornError
+	if !errors.As(err, &missingErr) || missingErr.Requester != "p1" || missingErr.AcornName != "ghost" {
+		t.FailNow()
+	}
+}
+
+func TestValidate_RuleForOverriddenAcorn(t *testing.T) {
+	impl := New()
+	Registry = impl
+
+	Registry.Register(para.New(&para.ParaImpl{Name: "p1"}))
+	Registry.Register(para.New(&para.ParaImpl{Name: "p2"}))
+	Registry.Create()
+	_ = Registry.AddSetupOrderRule(Registry.GetAcornByName("p1"), Registry.GetAcornByName("p2"))
+	Registry.CreateOverride("p1", &para.ParaImpl{Name: "p1"})
+	if Registry.Assemble() != nil {
+		t.FailNow()
+	}
+
+	err := impl.Validate()
+	var overriddenErr *OverriddenAcornError
+	if !errors.As(err, &overriddenErr) {
+		t.FailNow()
+	}
+	if err.Error() != "dependency p2 -[AddSetupOrderRule]-> p1 refers to an instance of acorn 'p1' that was replaced by CreateOverride()" {
+		t.Errorf("unexpected error message: %s", err.Error())
+	}
+}
+
+func TestValidate_RulesOfOverriddenAcornsInOrder(t *testing.T) {
+	impl := New()
+	Registry = impl
+
+	Registry.Register(para.New(&para.ParaImpl{Name: "p1"}))
+	Registry.Register(para.New(&para.ParaImpl{Name: "p2"}))
+	Registry.Register(para.New(&para.ParaImpl{Name: "p3"}))
+	Registry.Create()
+	_ = Registry.AddSetupOrderRule(Registry.GetAcornByName("p3"), Registry.GetAcornByName("p2"))
+	_ = Registry.AddSetupOrderRule(Registry.GetAcornByName("p3"), Registry.GetAcornByName("p1"))
+	Registry.CreateOverride("p2", &para.ParaImpl{Name: "p2"})
+	Registry.CreateOverride("p1", &para.ParaImpl{Name: "p1"})
+	if Registry.Assemble() != nil {
+		t.FailNow()
+	}
+
+	err := impl.Validate()
+	if err == nil {
+		t.FailNow()
+	}
+	expected := "dependency p1 -[AddSetupOrderRule]-> p3 refers to an instance of acorn 'p1' that was replaced by CreateOverride()\n" +
+		"dependency p2 -[AddSetupOrderRule]-> p3 refers to an instance of acorn 'p2' that was replaced by CreateOverride()"
+	if err.Error() != expected {
+		t.Errorf("unexpected error message: %s", err.Error())
+	}
+}